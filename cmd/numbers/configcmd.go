@@ -0,0 +1,84 @@
+package numbers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/milktart/milk/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// executeConfig runs the config subcommands
+func (h *Handler) executeConfig(args []string) error {
+	usage := func() {
+		fmt.Print("Usage: milk [--config <dir>] numbers config <subcommand>\n\n")
+		fmt.Println("Subcommands:")
+		fmt.Println("  show       Print the effective configuration after merging user files")
+		fmt.Print("\nUser files in the config directory are merged over the built-in defaults.\n")
+		fmt.Println("In patterns.yaml, `patterns: {<tier>: ...}` changes an existing tier's patterns")
+		fmt.Println("and `tiers: [...]` adds, changes (by name) or deletes whole tiers. Each tier's")
+		fmt.Println("patterns and each region in regions.yaml may be a plain list, which replaces")
+		fmt.Println("the default, or a mapping with add, remove and replace lists. A tier or region")
+		fmt.Println("with `delete: true` is removed.")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("no config subcommand given")
+	}
+
+	switch args[0] {
+	case "show":
+		return h.showConfig()
+	case "-h", "--help", "help":
+		usage()
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown config subcommand '%s'", args[0])
+	}
+}
+
+// showConfig prints the merged configuration as YAML
+func (h *Handler) showConfig() error {
+	if len(h.cfg.Files) == 0 {
+		fmt.Println("# Built-in defaults (no user files found)")
+	} else {
+		fmt.Println("# Built-in defaults merged with:")
+		for _, f := range h.cfg.Files {
+			fmt.Printf("#   %s\n", f)
+		}
+	}
+
+	fmt.Printf("# %d vanity words (words.txt)\n", len(h.cfg.Words))
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(struct {
+		Tiers     any `yaml:"tiers"`
+		Scoring   any `yaml:"scoring"`
+		Regions   any `yaml:"regions"`
+		Notifiers any `yaml:"notifiers"`
+	}{h.cfg.Tiers, h.cfg.Scoring, h.cfg.Regions, redactNotifiers(h.cfg.Notifiers)})
+}
+
+// redactNotifiers returns a copy of notifiers with passwords and header values
+// masked, so showing the configuration does not print secrets
+func redactNotifiers(notifiers []config.NotifierConfig) []config.NotifierConfig {
+	out := make([]config.NotifierConfig, len(notifiers))
+	for i, n := range notifiers {
+		if n.Password != "" {
+			n.Password = "***"
+		}
+		if len(n.Headers) > 0 {
+			headers := make(map[string]string, len(n.Headers))
+			for k := range n.Headers {
+				headers[k] = "***"
+			}
+			n.Headers = headers
+		}
+		out[i] = n
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package numbers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+func TestRedactNotifiers(t *testing.T) {
+	notifiers := []config.NotifierConfig{
+		{Type: "smtp", Host: "mail.example.com", Username: "me", Password: "hunter2"},
+		{Type: "webhook", URL: "https://example.com/hook", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{Type: "command", Command: []string{"say", "new number"}},
+	}
+	want := []config.NotifierConfig{
+		{Type: "smtp", Host: "mail.example.com", Username: "me", Password: "***"},
+		{Type: "webhook", URL: "https://example.com/hook", Headers: map[string]string{"Authorization": "***"}},
+		{Type: "command", Command: []string{"say", "new number"}},
+	}
+	if got := redactNotifiers(notifiers); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactNotifiers =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The loaded configuration is left as it was
+	if notifiers[0].Password != "hunter2" || notifiers[1].Headers["Authorization"] != "Bearer secret" {
+		t.Errorf("redactNotifiers changed its input: %+v", notifiers)
+	}
+}
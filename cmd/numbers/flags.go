@@ -0,0 +1,373 @@
+package numbers
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/milktart/milk/pkg/config"
+	httplib "github.com/milktart/milk/pkg/http"
+	"github.com/milktart/milk/pkg/notify"
+	"github.com/milktart/milk/pkg/query"
+	"github.com/milktart/milk/pkg/util"
+)
+
+// searchFlags holds the options shared by every subcommand that searches for numbers
+type searchFlags struct {
+	code        *string
+	region      *string
+	pattern     *string
+	minScore    *float64
+	locality    *string
+	state       *string
+	source      *string
+	concurrency *int
+	rateLimit   *time.Duration
+	retries     *int
+	cacheTTL    *time.Duration
+	refresh     *bool
+	offline     *bool
+	record      *string
+	replay      *string
+	notifyFile  *string
+
+	// shorthands maps each region with a shorthand flag (ex. --NYC) to whether it was given
+	shorthands map[string]*bool
+	// shadowed lists the regions without a shorthand because another flag has their name
+	shadowed []string
+}
+
+// displayOptions controls how results are printed
+type displayOptions struct {
+	explain  bool
+	allTiers bool
+	noColor  bool
+	quiet    bool
+	output   string
+	formats  util.NumberFormats
+}
+
+// Result formats accepted by --output
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputCSV    = "csv"
+	outputNDJSON = "ndjson"
+)
+
+// addDisplayFlags registers the result display options on fs
+func addDisplayFlags(fs *flag.FlagSet) *displayOptions {
+	d := &displayOptions{}
+	fs.BoolVar(&d.explain, "explain", false, "Show the name of the pattern(s) each number matched")
+	fs.BoolVar(&d.allTiers, "all-tiers", false, "List numbers under every tier they match, not just the best one")
+	fs.BoolVar(&d.noColor, "no-color", false, "Disable colored output (also off when NO_COLOR is set or output is not a terminal)")
+	fs.BoolVar(&d.quiet, "quiet", false, "Don't show search progress")
+	fs.Var(&d.formats, "format", "Number `format`: "+strings.Join(util.NumberFormatNames(), ", ")+
+		" or a template such as '{{.AreaCode}}.{{.Exchange}}.{{.Line}}'; repeat or comma separate to show several (default "+
+		util.DefaultNumberFormat+")")
+	fs.StringVar(&d.output, "output", outputText, "Result format: text, json, csv or ndjson (progress then goes to stderr)")
+	return d
+}
+
+// setup validates the display options and decides whether to use color
+func (d *displayOptions) setup() error {
+	switch d.output {
+	case outputText, outputJSON, outputCSV, outputNDJSON:
+	default:
+		return fmt.Errorf("unknown output format '%s' (available: text, json, csv, ndjson)", d.output)
+	}
+	setupColor(d.noColor)
+	return nil
+}
+
+// log returns where progress and other status messages go: stdout for text
+// results, stderr otherwise so stdout holds only the results
+func (d displayOptions) log() *os.File {
+	if d.output == outputText {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// selectTiers resolves a -p list to tiers in their configured order.
+// An empty list or "all" selects every tier.
+func selectTiers(cfg *config.Config, list string) ([]config.CompiledTier, error) {
+	names := util.SplitList(list)
+	if len(names) == 0 {
+		return cfg.CompiledTiers, nil
+	}
+
+	want := make(map[string]bool, len(names))
+	for _, name := range names {
+		if strings.EqualFold(name, "all") {
+			return cfg.CompiledTiers, nil
+		}
+		t, ok := cfg.Tier(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown tier '%s' (available: %s)", name, strings.Join(cfg.TierNames(), ", "))
+		}
+		want[t.Name] = true
+	}
+
+	var tiers []config.CompiledTier
+	for _, t := range cfg.CompiledTiers {
+		if want[t.Name] {
+			tiers = append(tiers, t)
+		}
+	}
+	return tiers, nil
+}
+
+// printQuerySyntax describes the query grammar for --help
+func printQuerySyntax() {
+	fmt.Println("Queries (given with -c or as arguments):")
+	fmt.Println("  212        area code")
+	fmt.Println("  212-555    area code and exchange")
+	fmt.Println("  5555       4 to 7 digits found anywhere in the number")
+	fmt.Println("  ~8449988   partial, passed to the provider as written")
+}
+
+// printRegions lists the configured regions and their area codes for --help,
+// noting the ones whose name is taken by another flag and so have no shorthand
+func printRegions(cfg *config.Config, f *searchFlags) {
+	fmt.Println("Regions (from regions.yaml):")
+	for _, name := range cfg.RegionNames() {
+		fmt.Printf("  %-10s %s\n", name, strings.Join(cfg.Regions[name], " "))
+	}
+	if len(f.shadowed) > 0 {
+		fmt.Print("\nThese regions share a name with another option, so have no shorthand flag\n")
+		fmt.Printf("and must be given with -r: %s\n", strings.Join(f.shadowed, ", "))
+	}
+}
+
+// search is a fully resolved set of search options
+type search struct {
+	queries  []query.Query
+	tiers    []config.CompiledTier
+	filter   filter
+	opts     SearchOptions
+	notifier *notify.Dispatcher
+}
+
+// addSearchFlags registers the search options on fs. The region shorthands are
+// added separately by addRegionFlags once every other flag is registered.
+func addSearchFlags(fs *flag.FlagSet) *searchFlags {
+	f := &searchFlags{shorthands: make(map[string]*bool)}
+
+	f.code = fs.String("c", "", "Comma or space separated list of queries: area codes, 212-555, 5555 or ~partial (ex. -c 212,415,808)")
+	fs.StringVar(f.code, "code", "", "Same as -c")
+
+	f.region = fs.String("r", "", "Region or region expression (ex. -r Canada, -r 'Canada+TX-416-418')")
+	fs.StringVar(f.region, "region", "", "Same as -r")
+
+	f.pattern = fs.String("p", "", "Tier(s) to report, as named in patterns.yaml (ex. -p VIP,platinum)")
+	fs.StringVar(f.pattern, "pattern", "", "Same as -p")
+
+	f.minScore = fs.Float64("min-score", 0, "Only report numbers scoring at least this much")
+	f.locality = fs.String("locality", "", "Only report numbers whose provider places them in a matching city or town (ex. --locality Brooklyn)")
+	// Not --state, which watch uses for its state file
+	f.state = fs.String("in-state", "", "Only report numbers whose provider places them in this state or province (ex. --in-state NY; "+
+		"named so as not to clash with watch's --state file)")
+
+	f.source = fs.String("source", httplib.DefaultSource,
+		"Number provider(s) to search (available: "+strings.Join(httplib.SourceNames(), ", ")+")")
+
+	f.concurrency = fs.Int("concurrency", 4, "Maximum number of queries searched at once")
+	f.rateLimit = fs.Duration("rate-limit", 250*time.Millisecond, "Minimum delay between requests to the same host")
+	f.retries = fs.Int("retries", httplib.DefaultRetryPolicy.Retries, "Times to retry a query after a timeout, 5xx or 429 response")
+
+	f.cacheTTL = fs.Duration("cache-ttl", httplib.DefaultCacheTTL, "How long to reuse cached results (cache in "+httplib.DefaultCacheDir()+")")
+	f.refresh = fs.Bool("refresh", false, "Ignore cached results and fetch everything again")
+	f.offline = fs.Bool("offline", false, "Use only cached results, whatever their age, and never fetch")
+	f.record = fs.String("record", "", "Save every provider response to this cassette `file`, bypassing the cache")
+	f.replay = fs.String("replay", "", "Answer every request from this cassette `file` made with --record, bypassing the cache")
+
+	f.notifyFile = fs.String("notify-config", "", "Notifier settings file to use instead of the configured notify.yaml")
+
+	return f
+}
+
+// addRegionFlags registers a shorthand flag for every region in cfg, skipping
+// those whose name clashes with a flag already on fs. Call it after the other
+// flags of the command are registered.
+func (f *searchFlags) addRegionFlags(fs *flag.FlagSet, cfg *config.Config) {
+	for _, name := range cfg.RegionNames() {
+		if name == "default" {
+			continue
+		}
+		if fs.Lookup(name) != nil {
+			f.shadowed = append(f.shadowed, name)
+			continue
+		}
+		f.shorthands[name] = fs.Bool(name, false, "Shorthand for -r "+name)
+	}
+}
+
+// regionChoice returns the region picked by -r or a shorthand flag.
+// Picking more than one different region is an error.
+func (f *searchFlags) regionChoice() (string, error) {
+	var picked []string
+	region := *f.region
+	if region != "" {
+		picked = append(picked, "-r "+region)
+	}
+	for _, name := range slices.Sorted(maps.Keys(f.shorthands)) {
+		if !*f.shorthands[name] {
+			continue
+		}
+		picked = append(picked, "--"+name)
+		if region != "" && region != name {
+			return "", fmt.Errorf("conflicting regions given: %s (choose one)", strings.Join(picked, ", "))
+		}
+		region = name
+	}
+	return region, nil
+}
+
+// parseArgs parses fs, allowing flags to follow positional arguments, and returns the positional ones
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseQueries validates each query, dropping repeats
+func parseQueries(list []string) ([]query.Query, error) {
+	var queries []query.Query
+	seen := make(map[string]bool)
+	for _, s := range list {
+		q, err := query.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[q.Text] {
+			seen[q.Text] = true
+			queries = append(queries, q)
+		}
+	}
+	return queries, nil
+}
+
+// resolve turns the parsed flags and positional args into a search
+func (f *searchFlags) resolve(cfg *config.Config, args []string) (*search, error) {
+	region, err := f.regionChoice()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []httplib.NumberSource
+	for _, name := range util.SplitList(*f.source) {
+		src, err := httplib.LookupSource(name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no number source specified")
+	}
+
+	notifiers := cfg.Notifiers
+	if *f.notifyFile != "" {
+		if notifiers, err = config.LoadNotifiers(*f.notifyFile); err != nil {
+			return nil, err
+		}
+	}
+	notifier, err := notify.New(notifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := util.SplitList(*f.code)
+	tiers, err := selectTiers(cfg, *f.pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	codes = append(codes, args...)
+
+	if region != "" {
+		regionCodes, err := cfg.GetRegionCodes(region)
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) == 0 {
+			codes = regionCodes
+		}
+	}
+
+	if len(codes) == 0 {
+		if _, ok := cfg.Regions["default"]; !ok {
+			return nil, fmt.Errorf("no area codes specified and default region not found")
+		}
+		if codes, err = cfg.GetRegionCodes("default"); err != nil {
+			return nil, err
+		}
+	}
+
+	queries, err := parseQueries(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	if *f.retries < 0 {
+		return nil, fmt.Errorf("retries must not be negative, got %d", *f.retries)
+	}
+	retry := httplib.DefaultRetryPolicy
+	retry.Retries = *f.retries
+
+	mode := httplib.CacheNormal
+	switch {
+	case *f.refresh && *f.offline:
+		return nil, fmt.Errorf("--refresh and --offline cannot be used together")
+	case *f.refresh:
+		mode = httplib.CacheRefresh
+	case *f.offline:
+		mode = httplib.CacheOffline
+	}
+	cache := httplib.NewCache(httplib.DefaultCacheDir(), *f.cacheTTL, mode)
+
+	// Cassettes replace the cache so recordings hold real responses and replays are repeatable
+	var transport http.RoundTripper
+	switch {
+	case *f.record != "" && *f.replay != "":
+		return nil, fmt.Errorf("--record and --replay cannot be used together")
+	case *f.record != "":
+		transport, cache = httplib.NewRecorder(*f.record, nil), nil
+	case *f.replay != "":
+		cassette, err := httplib.LoadCassette(*f.replay)
+		if err != nil {
+			return nil, err
+		}
+		transport, cache = cassette, nil
+	}
+
+	return &search{
+		queries: queries,
+		tiers:   tiers,
+		filter:  filter{minScore: *f.minScore, locality: *f.locality, state: *f.state},
+		opts: SearchOptions{
+			Sources:     sources,
+			Concurrency: *f.concurrency,
+			RateLimit:   *f.rateLimit,
+			Retry:       retry,
+			Cache:       cache,
+			Transport:   transport,
+		},
+		notifier: notifier,
+	}, nil
+}
@@ -3,10 +3,8 @@ package numbers
 import (
 	"flag"
 	"fmt"
-	"strings"
 
 	"github.com/milktart/milk/pkg/config"
-	"github.com/milktart/milk/pkg/util"
 )
 
 // Handler processes the numbers subcommand
@@ -25,75 +23,77 @@ func NewHandler(cfg *config.Config) *Handler {
 
 // Execute runs the numbers command with the provided arguments
 func (h *Handler) Execute(args []string) error {
-	codeFlag := h.FlagSet.String("c", "", "Comma or space separated list of area codes (ex. -c 212,415,808)")
-	h.FlagSet.StringVar(codeFlag, "code", "", "Same as -c")
-
-	regionFlag := h.FlagSet.String("r", "", "Region filter (ex. -r Canada)")
-	h.FlagSet.StringVar(regionFlag, "region", "", "Same as -r")
-
-	patternFlag := h.FlagSet.String("p", "", "Pattern type(s) to search (ex. -p VIP,platinum)")
-	h.FlagSet.StringVar(patternFlag, "pattern", "", "Same as -p")
+	if len(args) > 0 {
+		switch args[0] {
+		case "scan":
+			return h.executeScan(args[1:])
+		case "watch":
+			return h.executeWatch(args[1:])
+		case "patterns":
+			return h.executePatterns(args[1:])
+		case "config":
+			return h.executeConfig(args[1:])
+		}
+	}
 
-	canadaFlag := h.FlagSet.Bool("Canada", false, "Shorthand for -r Canada")
-	CAFlag := h.FlagSet.Bool("CA", false, "Shorthand for -r CA")
-	NYFlag := h.FlagSet.Bool("NY", false, "Shorthand for -r NY")
-	NYCFlag := h.FlagSet.Bool("NYC", false, "Shorthand for -r NYC")
-	TXFlag := h.FlagSet.Bool("TX", false, "Shorthand for -r TX")
+	sf := addSearchFlags(h.FlagSet)
+	display := addDisplayFlags(h.FlagSet)
+	notifyFlag := h.FlagSet.Bool("notify", false, "Send the numbers found to the configured notifiers")
+	sf.addRegionFlags(h.FlagSet, h.cfg)
 
 	h.FlagSet.Usage = func() {
-		fmt.Fprintf(h.FlagSet.Output(), "Usage: milk numbers [options]\n\n")
-		fmt.Println("Search for special phone numbers by area code and pattern.\n")
+		fmt.Fprintf(h.FlagSet.Output(), "Usage: milk numbers [options]\n")
+		fmt.Fprintf(h.FlagSet.Output(), "       milk numbers <subcommand> [options]\n\n")
+		fmt.Println("Search for special phone numbers by area code and pattern.")
+		fmt.Print("Exits with status 1 if any query fails, after printing what the rest found.\n\n")
+		fmt.Println("Subcommands:")
+		fmt.Println("  scan       Classify numbers from a file or stdin without searching")
+		fmt.Println("  watch      Poll the search and report only newly available numbers")
+		fmt.Println("  patterns   Tools for writing patterns (patterns test, patterns lint)")
+		fmt.Print("  config     Inspect the effective configuration (config show)\n\n")
 		fmt.Println("Options:")
 		h.FlagSet.PrintDefaults()
+		fmt.Println()
+		printQuerySyntax()
+		fmt.Println()
+		printRegions(h.cfg, sf)
 		fmt.Println("\nExamples:")
 		fmt.Println("  milk numbers -c 212 415 808 -r Canada -p VIP,platinum")
 		fmt.Println("  milk numbers --code 212,415,808 --region TX --pattern VIP")
+		fmt.Println("  milk numbers -r 'Canada+TX-416-418'")
 		fmt.Println("  milk numbers --Canada -c 416 604")
+		fmt.Println("  milk numbers --Canada --concurrency 8")
+		fmt.Println("  milk numbers -c 212 --source jmp")
+		fmt.Println("  milk numbers 212-555 5555 ~8449988")
+		fmt.Println("  milk numbers --Canada --min-score 60")
+		fmt.Println("  milk numbers -c 718,347 --locality Brooklyn")
+		fmt.Println("  milk numbers -c 212 -p VIP --explain")
+		fmt.Println("  milk numbers -c 212 --format e164,dashed")
+		fmt.Println("  milk numbers -r NYC --offline -p VIP,platinum")
+		fmt.Println("  milk numbers -r NYC --output csv > numbers.csv")
+		fmt.Println("  milk numbers -r NYC -p VIP --notify --notify-config ~/notify.yaml")
 	}
 
-	if err := h.FlagSet.Parse(args); err != nil {
+	positional, err := parseArgs(h.FlagSet, args)
+	if err != nil {
 		return err
 	}
-
-	region := *regionFlag
-	if *canadaFlag {
-		region = "Canada"
-	}
-	if *CAFlag {
-		region = "CA"
-	}
-	if *NYFlag {
-		region = "NY"
-	}
-	if *NYCFlag {
-		region = "NYC"
-	}
-	if *TXFlag {
-		region = "TX"
-	}
-
-	codes := util.SplitList(*codeFlag)
-	patternTypes := util.SplitList(*patternFlag)
-
-	for _, arg := range h.FlagSet.Args() {
-		if !strings.HasPrefix(arg, "-") && len(arg) <= 5 {
-			codes = append(codes, arg)
-		}
+	if err := display.setup(); err != nil {
+		return err
 	}
 
-	if region != "" && len(codes) == 0 {
-		if rc := h.cfg.GetRegionCodes(region); rc != nil {
-			codes = rc
-		}
+	srch, err := sf.resolve(h.cfg, positional)
+	if err != nil {
+		return err
 	}
 
-	if len(codes) == 0 {
-		codes = h.cfg.GetRegionCodes("default")
-		if codes == nil {
-			return fmt.Errorf("no area codes specified and default region not found")
+	// Numbers found by the queries that succeeded are still notified
+	hits, err := GetNumbersFiltered(srch.queries, srch.tiers, srch.filter, srch.opts, *display)
+	if *notifyFlag {
+		if srch.notifier.Empty() {
+			return fmt.Errorf("--notify given but no notifiers are configured")
 		}
+		sendNotifications(srch.notifier, hits)
 	}
-
-	GetNumbersFiltered(codes, patternTypes)
-	return nil
+	return err
 }
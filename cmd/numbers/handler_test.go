@@ -0,0 +1,179 @@
+package numbers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/http/jmptest"
+)
+
+// update re-records the cassettes from the fake jmp.chat and rewrites the golden files
+var update = flag.Bool("update", false, "re-record cassettes and rewrite golden files")
+
+// jmpPages holds the pages served by the fake jmp.chat when recording
+const jmpPages = "../../pkg/http/testdata/jmp"
+
+// handlerTests run the numbers command and compare everything it prints with
+// testdata/golden/<name>.golden. Searches are replayed from
+// testdata/cassettes/<name>.json.
+var handlerTests = []struct {
+	name     string
+	args     []string
+	searches bool           // whether the command fetches, and so needs a cassette
+	fail     map[string]int // queries the fake jmp.chat fails, with the status, when recording
+	err      string         // the error Execute returns, if any
+}{
+	{name: "area_code", args: []string{"212"}, searches: true},
+	{name: "queries", args: []string{"-c", "212-555,718", "5555"}, searches: true},
+	{name: "locality", args: []string{"718", "--locality", "brook"}, searches: true},
+	{name: "state", args: []string{"~5555", "--in-state", "il"}, searches: true},
+	{name: "explain_formats", args: []string{"212", "-p", "VIP,platinum", "--explain", "--format", "e164,dashed"}, searches: true},
+	{name: "all_tiers", args: []string{"212", "--all-tiers"}, searches: true},
+	{name: "json", args: []string{"212", "718", "--output", "json"}, searches: true},
+	{name: "csv", args: []string{"718", "--output", "csv"}, searches: true},
+	{
+		name:     "failure",
+		args:     []string{"212", "415", "--retries", "0"},
+		searches: true,
+		fail:     map[string]int{"415": http.StatusServiceUnavailable},
+		err:      "1 of 2 queries failed: 415",
+	},
+	{name: "scan", args: []string{"scan", "testdata/scan.txt"}},
+}
+
+func TestHandlerExecute(t *testing.T) {
+	cfg, err := config.LoadFromBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NO_COLOR", "1")
+
+	for _, tt := range handlerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			cassette := filepath.Join("testdata", "cassettes", tt.name+".json")
+			golden := filepath.Join("testdata", "golden", tt.name+".golden")
+
+			args := tt.args
+			if tt.searches {
+				args = append([]string{"--quiet", "--rate-limit", "0"}, args...)
+				if *update {
+					recordCassette(t, cfg, cassette, args, tt.fail, tt.err)
+				}
+				args = append(args, "--replay", cassette)
+			}
+
+			got, err := execute(t, cfg, args)
+			if !matchErr(err, tt.err) {
+				t.Fatalf("Execute(%q) error = %v, want %q", args, err, tt.err)
+			}
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Execute(%q) printed:\n%s\nwant (%s):\n%s", args, got, golden, want)
+			}
+		})
+	}
+}
+
+// recordCassette runs the command against the fake jmp.chat, saving its responses to cassette
+func recordCassette(t *testing.T, cfg *config.Config, cassette string, args []string, fail map[string]int, wantErr string) {
+	t.Helper()
+	srv := jmptest.NewServer(jmpPages)
+	defer srv.Close()
+	for query, status := range fail {
+		srv.Fail(query, status)
+	}
+
+	// The recorder sends requests through http.DefaultTransport; point it at the fake
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = srv.Transport()
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	if err := os.Remove(cassette); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, cfg, append(args, "--record", cassette)); !matchErr(err, wantErr) {
+		t.Fatalf("recording %s: %v", cassette, err)
+	}
+}
+
+// matchErr reports whether err is nil and want empty, or err's message is want
+func matchErr(err error, want string) bool {
+	if err == nil {
+		return want == ""
+	}
+	return err.Error() == want
+}
+
+// execute runs the numbers command with args, returning what it wrote to stdout and stderr
+func execute(t *testing.T, cfg *config.Config, args []string) ([]byte, error) {
+	t.Helper()
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, out
+	err = NewHandler(cfg).Execute(args)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	printed, readErr := os.ReadFile(out.Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return printed, err
+}
+
+func TestWatch(t *testing.T) {
+	cfg, err := config.LoadFromBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NO_COLOR", "1")
+	state := filepath.Join(t.TempDir(), "watch.json")
+	args := []string{"watch", "--quiet", "--rate-limit", "0", "--exit-on-new", "--state", state,
+		"--replay", filepath.Join("testdata", "cassettes", "area_code.json")}
+
+	if _, err := execute(t, cfg, append(args, "--output", "json", "212")); err == nil {
+		t.Error("watch --output json succeeded, want an error")
+	}
+
+	got, err := execute(t, cfg, append(args, "--output", "ndjson", "212"))
+	if !errors.Is(err, ErrNewNumbers) {
+		t.Fatalf("watch: %v, want ErrNewNumbers\n%s", err, got)
+	}
+	lines := bytes.Split(bytes.TrimSpace(got), []byte("\n"))
+	for _, line := range lines {
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatalf("watch printed %q: %v", line, err)
+		}
+		if !slices.Equal(r.Queries, []string{"212"}) {
+			t.Errorf("record for %s has queries %q, want [212]", r.E164, r.Queries)
+		}
+	}
+	if _, err := os.Stat(state); err != nil {
+		t.Errorf("state file was not written: %v", err)
+	}
+}
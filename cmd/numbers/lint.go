@@ -0,0 +1,562 @@
+package numbers
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/phone"
+	"github.com/milktart/milk/pkg/util"
+)
+
+// Kinds of lint finding
+const (
+	lintDuplicate    = "duplicate"
+	lintAnchorable   = "anchorable"
+	lintShadowed     = "shadowed"
+	lintCatastrophic = "catastrophic"
+)
+
+// lintMinHits is how many corpus numbers a pattern must match before its
+// matches are used to judge anchoring and shadowing
+const lintMinHits = 3
+
+// lintSlowMatch is the match time above which a pattern is reported as slow.
+// Well-behaved patterns take microseconds on a phone number.
+const lintSlowMatch = 10 * time.Millisecond
+
+// adversarialInputs are long, repetitive national numbers that make
+// backtracking patterns try the most ways to match
+var adversarialInputs = []string{
+	"000000000000000",
+	"121212121212121",
+	"112211221122112",
+	"123123123123123",
+	"012345678901234",
+}
+
+// lintFinding is a problem found with a configured pattern
+type lintFinding struct {
+	tier    string
+	pattern config.CompiledPattern
+	kind    string
+	message string
+}
+
+// warning reports whether f only costs speed or tier placement, rather than
+// risking a hang or double counting, and so does not fail the lint
+func (f lintFinding) warning() bool {
+	return f.kind == lintAnchorable || f.kind == lintShadowed
+}
+
+// lintTarget is a pattern being linted with the corpus numbers it matched
+type lintTarget struct {
+	tier    int
+	pattern config.CompiledPattern
+	hits    []bool // hits[i] is whether it matched corpus[i]
+	count   int    // number of hits
+	checked bool   // whether it was matched against the corpus
+
+	// slow is the first corpus number that took over lintSlowMatch to match,
+	// which stops the matching; err is set if the match timed out
+	slow     string
+	slowTime time.Duration
+	err      error
+
+	// whether every match starts at the first digit, and ends at the last
+	allStart, allEnd bool
+}
+
+// executePatternsLint checks the configured patterns for common mistakes
+func (h *Handler) executePatternsLint(args []string) error {
+	fs := flag.NewFlagSet("numbers patterns lint", flag.ExitOnError)
+	countFlag := fs.Int("n", 5000, "How many numbers to generate for the corpus the patterns are compared on")
+	seedFlag := fs.Uint64("seed", 1, "Seed for the generated corpus")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: milk numbers patterns lint [options]\n\n")
+		fmt.Print("Check the configured patterns for problems:\n\n")
+		fmt.Println("  duplicate      the same regex appears more than once")
+		fmt.Println("  anchorable     a leading .* or missing ^/$ makes the engine retry from every digit")
+		fmt.Println("  shadowed       every number it matches is matched by another pattern in the same or a better tier")
+		fmt.Println("  catastrophic   nested or adjacent quantifiers that can backtrack heavily, or slow matches")
+		fmt.Print("\nAnchoring and shadowing are judged on a generated corpus of NANP numbers, so\n")
+		fmt.Print("patterns for other countries and ones that rarely match are only checked statically,\n")
+		fmt.Print("and anchors are only suggested for patterns limited to country \"1\".\n")
+		fmt.Print("Anchorable and shadowed patterns are warnings; exits with an error if a\n")
+		fmt.Print("duplicate or catastrophic pattern is found.\n\n")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println("\nExamples:")
+		fmt.Println("  milk numbers patterns lint")
+		fmt.Println("  milk --config ./milk numbers patterns lint -n 100000")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupColor(false)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *countFlag < 1 {
+		return fmt.Errorf("-n must be positive, got %d", *countFlag)
+	}
+
+	corpus, err := lintCorpus(*countFlag, *seedFlag)
+	if err != nil {
+		return err
+	}
+	findings, unchecked := lintPatterns(h.cfg.CompiledTiers, corpus)
+
+	patterns := 0
+	for _, t := range h.cfg.CompiledTiers {
+		patterns += len(t.Patterns)
+	}
+	fmt.Printf("Linted %d patterns in %d tiers against %d numbers\n\n", patterns, len(h.cfg.CompiledTiers), len(corpus))
+
+	flagged, warnings := 0, 0
+	for i, f := range findings {
+		if i == 0 || f.pattern.Regex != findings[i-1].pattern.Regex || f.tier != findings[i-1].tier {
+			flagged++
+			name := fmt.Sprintf("%s '%s'", f.tier, f.pattern.Regex)
+			if f.pattern.Label != "" {
+				name += " (" + f.pattern.Label + ")"
+			}
+			fmt.Println(name)
+		}
+		color := util.RED
+		if f.warning() {
+			color = util.YELLOW
+			warnings++
+		}
+		fmt.Printf("  %s %s\n", util.Paint(color, f.kind+":"), f.message)
+	}
+	if len(findings) > 0 {
+		fmt.Println()
+	}
+	if unchecked > 0 {
+		fmt.Printf("%d pattern(s) matched fewer than %d corpus numbers and were not checked for anchoring or shadowing\n",
+			unchecked, lintMinHits)
+	}
+
+	switch {
+	case len(findings) == 0:
+		fmt.Println("No problems found")
+	case warnings < len(findings):
+		return fmt.Errorf("%d problem(s) and %d warning(s) found in %d pattern(s)", len(findings)-warnings, warnings, flagged)
+	default:
+		fmt.Printf("%d warning(s) found in %d pattern(s)\n", warnings, flagged)
+	}
+	return nil
+}
+
+// lintPatterns checks every pattern in tiers, comparing NANP patterns on corpus.
+// Findings are grouped by pattern in configuration order. It also returns how
+// many NANP patterns matched too little of the corpus to judge.
+func lintPatterns(tiers []config.CompiledTier, corpus []string) ([]lintFinding, int) {
+	var targets []*lintTarget
+	for i, t := range tiers {
+		for _, p := range t.Patterns {
+			targets = append(targets, matchCorpus(i, p, corpus))
+		}
+	}
+
+	var findings []lintFinding
+	unchecked := 0
+	first := make(map[string]*lintTarget)
+	for _, t := range targets {
+		tier := tiers[t.tier].Name
+		add := func(kind, format string, args ...any) {
+			findings = append(findings, lintFinding{tier: tier, pattern: t.pattern, kind: kind, message: fmt.Sprintf(format, args...)})
+		}
+
+		key := t.pattern.Country + "\x00" + t.pattern.Regex
+		if prev, dup := first[key]; dup {
+			add(lintDuplicate, "same regex as the %s pattern above", tiers[prev.tier].Name)
+		} else {
+			first[key] = t
+		}
+
+		if suggestion, ok := dropDotStar(t.pattern.Regex); ok {
+			add(lintAnchorable, "unanchored .* only adds backtracking; '%s' matches the same numbers", suggestion)
+		} else if t.count >= lintMinHits && t.pattern.Country == phone.NANP {
+			// The corpus only has 10-digit NANP numbers, so anchoring a pattern
+			// that applies to every country could break it on longer numbers
+			if suggestion, where, ok := anchorSuggestion(t.pattern.Regex, t); ok {
+				add(lintAnchorable, "every corpus match %s; anchored as '%s' it can fail fast", where, suggestion)
+			}
+		}
+
+		if t.checked && t.count < lintMinHits {
+			unchecked++
+		} else if t.count >= lintMinHits {
+			if msg, ok := shadowedBy(t, targets, tiers); ok {
+				add(lintShadowed, "%s", msg)
+			}
+		}
+
+		for _, risk := range backtrackRisks(t.pattern.Regex) {
+			add(lintCatastrophic, "%s", risk)
+		}
+		input, elapsed, err := t.slow, t.slowTime, t.err
+		if input == "" {
+			input, elapsed, err = slowestMatch(t.pattern)
+		}
+		if err != nil {
+			add(lintCatastrophic, "timed out after %s on %s", config.MatchTimeout, input)
+		} else if elapsed > lintSlowMatch {
+			add(lintCatastrophic, "took %s to match %s", elapsed.Round(time.Microsecond), input)
+		}
+	}
+	return findings, unchecked
+}
+
+// matchCorpus matches p against the NANP numbers of corpus, if it applies to them
+func matchCorpus(tier int, p config.CompiledPattern, corpus []string) *lintTarget {
+	t := &lintTarget{tier: tier, pattern: p}
+	if !p.AppliesTo(phone.NANP) {
+		return t
+	}
+	t.checked = true
+	t.hits = make([]bool, len(corpus))
+	t.allStart, t.allEnd = true, true
+	for i, n := range corpus {
+		nsn := phone.NationalOf(n)
+		start := time.Now()
+		m, err := p.Re.FindStringMatch(nsn)
+		if elapsed := time.Since(start); err != nil || elapsed > lintSlowMatch {
+			if elapsed, err = timeMatch(p, nsn); err != nil || elapsed > lintSlowMatch {
+				// Every other number would likely take as long
+				t.checked, t.slow, t.slowTime, t.err = false, n, elapsed, err
+				return t
+			}
+		}
+		if m == nil {
+			continue
+		}
+		t.hits[i] = true
+		t.count++
+		t.allStart = t.allStart && m.Index == 0
+		t.allEnd = t.allEnd && m.Index+m.Length == len(nsn)
+	}
+	return t
+}
+
+// anchorSuggestion returns regex with the ^ and $ anchors it lacks added, when
+// every corpus match of t starts at the first digit or ends at the last, and
+// what the matches have in common. No $ is suggested after a trailing .* or
+// \d+, which reach the end anyway.
+func anchorSuggestion(regex string, t *lintTarget) (string, string, bool) {
+	start := !hasStartAnchor(regex) && t.allStart
+	end := !hasEndAnchor(regex) && t.allEnd && !endsGreedy(regex)
+	switch {
+	case start && end:
+		return "^" + regex + "$", "spans the whole number", true
+	case start:
+		return "^" + regex, "starts at the first digit", true
+	case end:
+		return regex + "$", "ends at the last digit", true
+	}
+	return "", "", false
+}
+
+// endsGreedy reports whether regex ends with . or \d repeated without bound
+func endsGreedy(regex string) bool {
+	for _, q := range []string{".*", ".+", `\d*`, `\d+`} {
+		if strings.HasSuffix(regex, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowedBy reports whether every corpus number t matches is also matched by
+// a single pattern in the same or a better tier, or else by the better tiers together
+func shadowedBy(t *lintTarget, targets []*lintTarget, tiers []config.CompiledTier) (string, bool) {
+	covered := make([]bool, len(t.hits))
+	for _, o := range targets {
+		if o == t || !o.checked || o.tier > t.tier {
+			continue
+		}
+		if o.pattern.Regex == t.pattern.Regex && o.pattern.Country == t.pattern.Country {
+			continue // reported as a duplicate
+		}
+		if subset(t.hits, o.hits) {
+			// Of two patterns in a tier that match the same numbers, only the later is reported
+			if o.tier == t.tier && subset(o.hits, t.hits) && slices.Index(targets, o) > slices.Index(targets, t) {
+				continue
+			}
+			return fmt.Sprintf("every number it matches in the corpus is also matched by %s '%s'",
+				tiers[o.tier].Name, o.pattern.Regex), true
+		}
+		if o.tier < t.tier {
+			for i, hit := range o.hits {
+				covered[i] = covered[i] || hit
+			}
+		}
+	}
+	if t.tier > 0 && subset(t.hits, covered) {
+		return "every number it matches in the corpus is already in a better tier, so it never places a number " +
+			"(its weight still counts toward scores)", true
+	}
+	return "", false
+}
+
+// subset reports whether every hit in a is also a hit in b
+func subset(a, b []bool) bool {
+	for i, hit := range a {
+		if hit && !b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// slowestMatch times p against the adversarial inputs, returning the slowest
+// and how long it took, or the input it timed out on
+func slowestMatch(p config.CompiledPattern) (string, time.Duration, error) {
+	var slowest string
+	var longest time.Duration
+	for _, input := range adversarialInputs {
+		elapsed, err := timeMatch(p, input)
+		if err != nil {
+			return input, elapsed, err
+		}
+		if elapsed > longest {
+			slowest, longest = input, elapsed
+		}
+	}
+	return slowest, longest, nil
+}
+
+// timeMatch returns the fastest of a few matches of p against s, so a garbage
+// collection or descheduling during one is not mistaken for a slow pattern
+func timeMatch(p config.CompiledPattern, s string) (time.Duration, error) {
+	fastest := time.Duration(math.MaxInt64)
+	for range 3 {
+		start := time.Now()
+		_, err := p.Re.MatchString(s)
+		elapsed := time.Since(start)
+		if err != nil {
+			return elapsed, err
+		}
+		fastest = min(fastest, elapsed)
+	}
+	return fastest, nil
+}
+
+// dropDotStar returns regex without the .* it starts with when it has no ^,
+// and the .* it ends with when it has no $. Neither changes which numbers match.
+func dropDotStar(regex string) (string, bool) {
+	out := regex
+	if strings.HasPrefix(out, ".*") && !strings.ContainsAny(out[2:min(3, len(out))], "*+?{") {
+		out = out[2:]
+	}
+	if strings.HasSuffix(out, ".*") && !strings.HasSuffix(out, `\.*`) {
+		out = out[:len(out)-2]
+	}
+	return out, out != regex && out != ""
+}
+
+// hasStartAnchor reports whether regex starts with ^ or \A
+func hasStartAnchor(regex string) bool {
+	return strings.HasPrefix(regex, "^") || strings.HasPrefix(regex, `\A`)
+}
+
+// hasEndAnchor reports whether regex ends with an unescaped $, \z or \Z
+func hasEndAnchor(regex string) bool {
+	return (strings.HasSuffix(regex, "$") && !strings.HasSuffix(regex, `\$`)) ||
+		strings.HasSuffix(regex, `\z`) || strings.HasSuffix(regex, `\Z`)
+}
+
+// reGroup tracks a group while scanning a regex in backtrackRisks
+type reGroup struct {
+	start      int    // index of its (
+	repeats    bool   // contains a quantifier allowing more than one repetition
+	alternates bool   // contains a |
+	unbounded  string // the previous atom if it had an unbounded quantifier
+}
+
+// backtrackRisks describes the constructs in regex that let a backtracking
+// engine try exponentially many ways to match: repeated groups that contain
+// quantifiers or alternations, and unbounded quantifiers next to each other
+func backtrackRisks(regex string) []string {
+	var risks []string
+	risk := func(format string, args ...any) {
+		if r := fmt.Sprintf(format, args...); !slices.Contains(risks, r) {
+			risks = append(risks, r)
+		}
+	}
+	stack := []*reGroup{{start: -1}}
+	for i := 0; i < len(regex); {
+		top := stack[len(stack)-1]
+		start := i
+		var inner *reGroup
+
+		switch regex[i] {
+		case '\\':
+			i = min(i+2, len(regex))
+		case '[':
+			i = classEnd(regex, i)
+		case '(':
+			stack = append(stack, &reGroup{start: i})
+			i = groupBodyStart(regex, i)
+			continue
+		case ')':
+			if len(stack) == 1 {
+				i++
+				continue
+			}
+			inner = top
+			stack = stack[:len(stack)-1]
+			top = stack[len(stack)-1]
+			start = inner.start
+			i++
+		case '|':
+			top.alternates = true
+			top.unbounded = ""
+			i++
+			continue
+		case '^', '$':
+			top.unbounded = ""
+			i++
+			continue
+		default:
+			i++
+		}
+
+		atom := regex[start:i]
+		quant, repeats, unbounded := readQuantifier(regex, i)
+		i += len(quant)
+		atom += quant
+
+		if inner != nil && repeats {
+			if inner.repeats {
+				risk("repeated group '%s' contains a quantifier, so the digits can be split between the repetitions in many ways", atom)
+			}
+			if inner.alternates {
+				risk("repeated group '%s' contains an alternation, so each repetition can retry every branch", atom)
+			}
+		}
+		if unbounded && top.unbounded != "" {
+			risk("adjacent unbounded quantifiers '%s' and '%s' can split the same digits in many ways", top.unbounded, atom)
+		}
+
+		top.repeats = top.repeats || repeats || (inner != nil && inner.repeats)
+		top.unbounded = ""
+		if unbounded {
+			top.unbounded = atom
+		}
+	}
+	return risks
+}
+
+// classEnd returns the index after the character class starting at regex[i]
+func classEnd(regex string, i int) int {
+	j := i + 1
+	if j < len(regex) && regex[j] == '^' {
+		j++
+	}
+	if j < len(regex) && regex[j] == ']' {
+		j++
+	}
+	for j < len(regex) && regex[j] != ']' {
+		if regex[j] == '\\' {
+			j++
+		}
+		j++
+	}
+	return min(j+1, len(regex))
+}
+
+// groupBodyStart returns the index of the first character inside the group
+// opening at regex[i], skipping ?:, ?=, ?!, ?<=, ?<! and ?<name>
+func groupBodyStart(regex string, i int) int {
+	j := i + 1
+	if j >= len(regex) || regex[j] != '?' {
+		return j
+	}
+	rest := regex[j:]
+	switch {
+	case strings.HasPrefix(rest, "?<=") || strings.HasPrefix(rest, "?<!"):
+		return j + 3
+	case strings.HasPrefix(rest, "?<") || strings.HasPrefix(rest, "?P<") || strings.HasPrefix(rest, "?'"):
+		if end := strings.IndexAny(rest[2:], ">'"); end >= 0 {
+			return j + 2 + end + 1
+		}
+	}
+	return min(j+2, len(regex))
+}
+
+// readQuantifier returns the quantifier at regex[i], if any, whether it allows
+// more than one repetition and whether it allows any number of them
+func readQuantifier(regex string, i int) (quant string, repeats, unbounded bool) {
+	if i >= len(regex) {
+		return "", false, false
+	}
+	switch regex[i] {
+	case '*', '+':
+		quant, repeats, unbounded = regex[i:i+1], true, true
+	case '?':
+		quant = "?"
+	case '{':
+		end := strings.IndexByte(regex[i:], '}')
+		if end < 0 {
+			return "", false, false
+		}
+		quant = regex[i : i+end+1]
+		lo, hi, comma := strings.Cut(quant[1:len(quant)-1], ",")
+		switch {
+		case !comma:
+			repeats = lo != "0" && lo != "1"
+		case hi == "":
+			repeats, unbounded = true, true
+		default:
+			repeats = hi != "0" && hi != "1"
+		}
+	default:
+		return "", false, false
+	}
+	// Lazy and possessive forms repeat the same way
+	if j := i + len(quant); j < len(regex) && (regex[j] == '?' || regex[j] == '+') {
+		quant += regex[j : j+1]
+	}
+	return quant, repeats, unbounded
+}
+
+// lintCorpus returns the numbers patterns are compared on: the built-in
+// sample, count random numbers and count numbers made of a few repeated digits,
+// which hit the repetition patterns far more often than random ones
+func lintCorpus(count int, seed uint64) ([]string, error) {
+	corpus, err := scanNumbers(bytes.NewReader(sampleNumbers))
+	if err != nil {
+		return nil, err
+	}
+	corpus = append(corpus, randomNumbers(count, seed)...)
+
+	rng := rand.New(rand.NewPCG(seed, seed+1))
+	for range count {
+		alphabet := make([]byte, 1+rng.IntN(3))
+		for i := range alphabet {
+			alphabet[i] = byte('0' + rng.IntN(10))
+		}
+		digits := make([]byte, 10)
+		for i := range digits {
+			digits[i] = alphabet[rng.IntN(len(alphabet))]
+		}
+		if rng.IntN(2) == 0 {
+			// Keep a realistic area code, repeating only the subscriber number
+			copy(digits, fmt.Sprintf("%d%02d", 2+rng.IntN(8), rng.IntN(100)))
+		}
+		corpus = append(corpus, "+"+phone.NANP+string(digits))
+	}
+	return corpus, nil
+}
@@ -0,0 +1,104 @@
+package numbers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/phone"
+)
+
+func TestBacktrackRisks(t *testing.T) {
+	tests := []struct {
+		regex string
+		want  []string // the quoted atoms each risk names
+	}{
+		{`((\d)(\d)(\2|\3){3})\1`, []string{`'(\2|\3){3}'`}},
+		{`(\d+)+$`, []string{`'(\d+)+'`}},
+		{`(?:\d{2})*`, []string{`'(?:\d{2})*'`}},
+		{`^\d*\d+0$`, []string{`'\d*' and '\d+'`}},
+		{`(a|b)?`, nil},
+		{`(\d)\1{3}$`, nil},
+		{`.*(\d)\1\1(\d)(\d)\2\3$`, nil},
+		{`[(*]+(\d)`, nil},
+		{`(\d{5})\1`, nil},
+		{`(\d?)+`, nil},
+		{`(?<x>\d|\d\d){2,}`, []string{`'(?<x>\d|\d\d){2,}'`}},
+	}
+	for _, tt := range tests {
+		got := backtrackRisks(tt.regex)
+		if len(got) != len(tt.want) {
+			t.Errorf("backtrackRisks(%q) = %q, want %d risk(s)", tt.regex, got, len(tt.want))
+			continue
+		}
+		for i, atom := range tt.want {
+			if !strings.Contains(got[i], atom) {
+				t.Errorf("backtrackRisks(%q)[%d] = %q, want it to name %s", tt.regex, i, got[i], atom)
+			}
+		}
+	}
+}
+
+func TestDropDotStar(t *testing.T) {
+	tests := []struct {
+		regex, want string
+		ok          bool
+	}{
+		{`.*8675309.*`, `8675309`, true},
+		{`.*(246)8\1$`, `(246)8\1$`, true},
+		{`^.*(246)8\1$`, ``, false},
+		{`.*?5$`, ``, false},
+		{`5\.*`, ``, false},
+		{`.*`, ``, false},
+		{`212.+`, ``, false},
+	}
+	for _, tt := range tests {
+		got, ok := dropDotStar(tt.regex)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("dropDotStar(%q) = %q, %v, want %q, %v", tt.regex, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLintPatterns(t *testing.T) {
+	compileFor := func(country, regex string) config.CompiledPattern {
+		re, err := config.CompileRegex(regex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config.CompiledPattern{Pattern: config.Pattern{Regex: regex, Country: country}, Re: re}
+	}
+	compile := func(regex string) config.CompiledPattern {
+		return compileFor("", regex)
+	}
+	tiers := []config.CompiledTier{
+		{Name: "Best", Patterns: []config.CompiledPattern{compile(`(\d)\1{3}$`), compile(`^212`)}},
+		{Name: "Good", Patterns: []config.CompiledPattern{
+			compile(`0000$`),
+			compile(`^212`),
+			compile(`(\d)(\d)(\d)(\d)(\d)\5\4\3\2\1`), // anchoring would break it for longer numbers
+			compileFor(phone.NANP, `[2-9]\d\d[2-9]\d{6}`),
+			compile(`(\d{2})+5$`),
+		}},
+	}
+	corpus, err := lintCorpus(2000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, _ := lintPatterns(tiers, corpus)
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.tier+" "+f.pattern.Regex+" "+f.kind)
+	}
+	want := []string{
+		`Good 0000$ shadowed`,
+		`Good ^212 duplicate`,
+		`Good [2-9]\d\d[2-9]\d{6} anchorable`,
+		`Good (\d{2})+5$ catastrophic`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lintPatterns found\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -2,115 +2,558 @@ package numbers
 
 import (
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
-	"unicode/utf8"
 
 	"github.com/milktart/milk/pkg/config"
 	httplib "github.com/milktart/milk/pkg/http"
+	"github.com/milktart/milk/pkg/notify"
+	"github.com/milktart/milk/pkg/phone"
+	"github.com/milktart/milk/pkg/query"
+	"github.com/milktart/milk/pkg/score"
 	"github.com/milktart/milk/pkg/util"
-	"golang.org/x/term"
+	"github.com/milktart/milk/pkg/vanity"
 )
 
-// GetNumbersFiltered searches for numbers matching specified patterns and area codes
-func GetNumbersFiltered(codes []string, patternTypes []string) {
-	fmt.Println("Searching these area codes or patterns:")
+// SearchOptions controls how area codes are fetched
+type SearchOptions struct {
+	Sources     []httplib.NumberSource // providers to query for every code
+	Concurrency int                    // maximum number of requests in flight
+	RateLimit   time.Duration          // minimum delay between requests to the same host
+	Retry       httplib.RetryPolicy    // how transient failures are retried
+	Cache       *httplib.Cache         // where results are cached, nil to always fetch
+	Transport   http.RoundTripper      // how requests are sent, nil for http.DefaultTransport
+}
+
+// sourceResult holds the numbers one provider returned for a query
+type sourceResult struct {
+	source string
+	query  string
+	httplib.Classification
+}
+
+// sourceFailure records why a provider failed for a query after any retries
+type sourceFailure struct {
+	source string
+	err    error
+}
+
+// codeResult holds the numbers found for a single query across providers
+type codeResult struct {
+	query    query.Query
+	found    []sourceResult
+	failures []sourceFailure
+}
+
+// failed reports whether any provider failed for the query
+func (r codeResult) failed() bool {
+	return len(r.failures) > 0
+}
+
+// tierNumbers holds the numbers found in one tier, highest score first
+type tierNumbers struct {
+	tier    config.CompiledTier
+	numbers []string
+}
+
+// tierHits holds the merged numbers of each selected tier, best tier first,
+// with the queries and providers that found them, their scores, pattern matches
+// and what the providers said about them
+type tierHits struct {
+	tiers     []tierNumbers
+	queries   map[string][]string
+	providers map[string][]string
+	scores    map[string]float64
+	matches   map[string][]httplib.PatternMatch
+	info      map[string]httplib.Number
+	timeouts  map[string][]httplib.PatternMatch // patterns that timed out on each number
+}
+
+// filter narrows the numbers reported
+type filter struct {
+	minScore float64
+	locality string // part of the locality, ignoring case (ex. brook matches Brooklyn)
+	state    string // state or province, ignoring case
+}
+
+// keep reports whether a number with the given score and provider details passes the filter.
+// Numbers whose provider gave no location fail any location filter.
+func (f filter) keep(score float64, info httplib.Number) bool {
+	if score < f.minScore {
+		return false
+	}
+	if f.locality != "" && !strings.Contains(strings.ToLower(info.Locality), strings.ToLower(f.locality)) {
+		return false
+	}
+	if f.state != "" && !strings.EqualFold(info.Region, f.state) {
+		return false
+	}
+	return true
+}
 
+// count returns the number of hits across all tiers
+func (h tierHits) count() int {
+	n := 0
+	for _, t := range h.tiers {
+		n += len(t.numbers)
+	}
+	return n
+}
+
+// GetNumbersFiltered searches for numbers matching specified patterns and queries
+// and returns the numbers found in each selected tier. If any query failed, the
+// numbers the others found are still printed and returned along with an error.
+func GetNumbersFiltered(
+	queries []query.Query,
+	tiers []config.CompiledTier,
+	f filter,
+	opts SearchOptions,
+	display displayOptions,
+) (tierHits, error) {
+	results := searchCodes("Searching these area codes or patterns:", queries, opts, display)
+	hits := mergeFound(collectFound(results), tiers, f, display.allTiers)
+	printHits(hits, display)
+	printSummary(display.log(), hits)
+	printTimeouts(display.log(), hits)
+	printFailures(display.log(), results)
+	return hits, failedErr(results)
+}
+
+// searchCodes fetches every query through the worker pool, showing progress under
+// header as it goes. Results are returned in the same order as queries.
+func searchCodes(header string, queries []query.Query, opts SearchOptions, display displayOptions) []codeResult {
 	cfg := config.Get()
-	client := &http.Client{Timeout: 10 * time.Second}
-	var allNumbers, allPlatinum, allVIP []string
-	var results []string
+	client := &http.Client{Timeout: 10 * time.Second, Transport: opts.Transport}
+	limiter := httplib.NewHostLimiter(opts.RateLimit)
+	sources := make([]httplib.NumberSource, len(opts.Sources))
+	for i, src := range opts.Sources {
+		sources[i] = opts.Cache.Wrap(limiter.Wrap(src))
+	}
+	prog := newProgress(display.log(), chooseProgress(display.quiet, display.log()), queries)
+	prog.Begin(header)
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(queries) {
+		workers = len(queries)
+	}
+
+	results := make([]codeResult, len(queries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				prog.Start(i)
+				results[i] = fetchCode(client, cfg, sources, opts.Retry, queries[i])
+				prog.Finish(i, !results[i].failed())
+			}
+		}()
+	}
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	prog.End()
 
-	for i, code := range codes {
-		// Prepare done/current/todo strings
-		done := strings.Join(results, ", ")
-		current := util.BLUEBLINK + code + util.NC
-		todo := ""
-		if i < len(codes)-1 {
-			todo = util.BLUE + strings.Join(codes[i+1:], ", ") + util.NC
+	return results
+}
+
+// collectFound flattens per-code results, keeping the order of the codes
+func collectFound(results []codeResult) []sourceResult {
+	var found []sourceResult
+	for _, r := range results {
+		found = append(found, r.found...)
+	}
+	return found
+}
+
+// fetchCode queries every source for a single query and classifies the numbers returned,
+// retrying transient failures. The query is reported as failed if any source fails;
+// results from the others are kept.
+func fetchCode(
+	client *http.Client,
+	cfg *config.Config,
+	sources []httplib.NumberSource,
+	retry httplib.RetryPolicy,
+	q query.Query,
+) codeResult {
+	res := codeResult{query: q}
+	for _, src := range sources {
+		var c httplib.Classification
+		err := retry.Do(func() error {
+			var err error
+			c, err = httplib.ExtractNumbers(
+				client,
+				src,
+				q,
+				cfg.CompiledTiers,
+				cfg.CompiledWords,
+			)
+			return err
+		})
+		if err != nil {
+			res.failures = append(res.failures, sourceFailure{source: src.Name(), err: err})
+			continue
 		}
+		res.found = append(res.found, sourceResult{source: src.Name(), query: q.Text, Classification: c})
+	}
+	return res
+}
 
-		// Print blinking line
-		lineParts := []string{}
-		lineClear := ""
+// mergeFound merges results, keeping only the given tiers and the numbers
+// passing the filter, and ranks each tier by score. Unless allTiers is set,
+// each number is kept only in the first (best) of the given tiers it matched.
+func mergeFound(found []sourceResult, tiers []config.CompiledTier, keep filter, allTiers bool) tierHits {
+	all := make([][]string, len(tiers))
+	queries := make(map[string][]string)
+	providers := make(map[string][]string)
+	matches := make(map[string][]httplib.PatternMatch)
+	info := make(map[string]httplib.Number)
+	timeouts := make(map[string][]httplib.PatternMatch)
+	for _, f := range found {
+		for n, m := range f.Timeouts {
+			timeouts[n] = m
+		}
+		for n, m := range f.Matches {
+			matches[n] = m
+		}
+		for n, i := range f.Info {
+			info[n] = mergeInfo(info[n], i)
+		}
+		for i, t := range tiers {
+			nums := f.Tiers[t.Name]
+			all[i] = append(all[i], nums...)
+			for _, n := range nums {
+				if f.query != "" && !contains(queries[n], f.query) {
+					queries[n] = append(queries[n], f.query)
+				}
+				if f.source != "" && !contains(providers[n], f.source) {
+					providers[n] = append(providers[n], f.source)
+				}
+			}
+		}
+	}
 
-		if done != "" {
-			lineParts = append(lineParts, done)
+	cfg := config.Get()
+	scores := make(map[string]float64)
+	rank := func(nums []string) []string {
+		var kept []string
+		for _, n := range httplib.DeduplicateAndSort(nums) {
+			if _, ok := scores[n]; !ok {
+				scores[n] = score.Score(n, cfg, matchedBy(matches[n]))
+			}
+			if keep.keep(scores[n], info[n]) {
+				kept = append(kept, n)
+			}
 		}
-		lineParts = append(lineParts, current)
-		if todo != "" {
-			lineParts = append(lineParts, todo)
+		sort.SliceStable(kept, func(i, j int) bool {
+			return scores[kept[i]] > scores[kept[j]]
+		})
+		return kept
+	}
+
+	hits := tierHits{queries: queries, providers: providers, scores: scores, matches: matches, info: info, timeouts: timeouts}
+	placed := make(map[string]bool)
+	for i, t := range tiers {
+		nums := rank(all[i])
+		if !allTiers {
+			var best []string
+			for _, n := range nums {
+				if !placed[n] {
+					placed[n] = true
+					best = append(best, n)
+				}
+			}
+			nums = best
 		}
-		line := strings.Join(lineParts, ", ")
+		hits.tiers = append(hits.tiers, tierNumbers{tier: t, numbers: nums})
+	}
+	return hits
+}
 
-		width, _, err := term.GetSize(int(os.Stdout.Fd()))
-		cols := utf8.RuneCountInString(util.StripANSI(line))
+// matchedBy reports for score.Score whether matches include a tier's pattern
+func matchedBy(matches []httplib.PatternMatch) func(tier, regex string) bool {
+	return func(tier, regex string) bool {
+		return slices.ContainsFunc(matches, func(m httplib.PatternMatch) bool {
+			return m.Tier == tier && m.Regex == regex
+		})
+	}
+}
 
-		if cols >= width {
-			lineClear = "\r\033[A  %s"
-		} else {
-			lineClear = "\r  %s"
+// mergeInfo fills in what a is missing from b, for numbers offered by several providers
+func mergeInfo(a, b httplib.Number) httplib.Number {
+	if a.E164 == "" {
+		return b
+	}
+	if a.Locality == "" && a.Region == "" {
+		a.Locality, a.Region = b.Locality, b.Region
+	}
+	if a.RateCenter == "" {
+		a.RateCenter = b.RateCenter
+	}
+	if a.Price == "" {
+		a.Price = b.Price
+	}
+	if len(b.Extra) > 0 {
+		// a's map may be shared with the provider's results, so add to a copy
+		extra := make(map[string]string, len(a.Extra)+len(b.Extra))
+		maps.Copy(extra, b.Extra)
+		maps.Copy(extra, a.Extra)
+		a.Extra = extra
+	}
+	return a
+}
+
+// printHits prints each tier's numbers under its heading, in the tier's color,
+// or writes them as records in the --output format
+func printHits(hits tierHits, display displayOptions) {
+	if display.output != outputText {
+		if err := writeRecords(os.Stdout, display.output, hits.records()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write results: %v\n", err)
 		}
+		return
+	}
 
-		fmt.Printf(lineClear, line)
+	printed := false
+	for _, t := range hits.tiers {
+		if len(t.numbers) == 0 {
+			continue
+		}
+		if printed {
+			fmt.Println()
+		}
+		title := fmt.Sprintf("%s numbers found:", t.tier.Name)
+		util.PrintNumbers(title, t.tier.Color, hits.entries(t.tier.Name, t.numbers), display.formats, display.explain)
+		printed = true
+	}
+	fmt.Println("")
+}
 
-		time.Sleep(500 * time.Millisecond) // simulate work before HTTP
+// printSummary prints how many numbers were found per tier and per area code,
+// or per country code for numbers outside the NANP
+func printSummary(w io.Writer, hits tierHits) {
+	if hits.count() == 0 {
+		fmt.Fprint(w, "No numbers found\n\n")
+		return
+	}
 
-		// Fetch numbers
-		url := "https://jmp.chat/tels?q=" + code
-		resp, err := client.Get(url)
-		result := ""
-		if err != nil {
-			result = util.RED + code + util.NC
-		} else {
-			nums, platinum, VIP, err := httplib.ExtractNumbers(
-				resp,
-				cfg.CompiledNotable,
-				cfg.CompiledPlatinum,
-				cfg.CompiledVIP,
-			)
-			if err != nil {
-				result = util.RED + code + util.NC
-			} else {
-				result = util.GREEN + code + util.NC
-				// Append pattern results
-				if len(patternTypes) == 0 {
-					allNumbers = append(allNumbers, nums...)
-					allPlatinum = append(allPlatinum, platinum...)
-					allVIP = append(allVIP, VIP...)
-				} else {
-					for _, pt := range patternTypes {
-						switch strings.ToLower(pt) {
-						case "vip":
-							allVIP = append(allVIP, VIP...)
-						case "platinum":
-							allPlatinum = append(allPlatinum, platinum...)
-						case "notable", "all":
-							allNumbers = append(allNumbers, nums...)
-						}
-					}
-				}
+	counts := make(map[string][]int) // area code -> count per tier
+	unique := make(map[string]map[string]bool)
+	var codes []string
+	for i, t := range hits.tiers {
+		for _, n := range t.numbers {
+			code := areaKey(n)
+			if counts[code] == nil {
+				counts[code] = make([]int, len(hits.tiers))
+				unique[code] = make(map[string]bool)
+				codes = append(codes, code)
+			}
+			counts[code][i]++
+			unique[code][n] = true
+		}
+	}
+	sort.Strings(codes)
+
+	fmt.Fprintf(w, "%-9s", "Summary")
+	for _, t := range hits.tiers {
+		fmt.Fprintf(w, " %9s", t.tier.Name)
+	}
+	fmt.Fprintf(w, " %9s\n", "Total")
+
+	totals := make([]int, len(hits.tiers))
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %-7s", code)
+		for i, c := range counts[code] {
+			fmt.Fprintf(w, " %9d", c)
+			totals[i] += c
+		}
+		fmt.Fprintf(w, " %9d\n", len(unique[code]))
+		for n := range unique[code] {
+			seen[n] = true
+		}
+	}
+
+	fmt.Fprintf(w, "  %-7s", "Total")
+	for _, c := range totals {
+		fmt.Fprintf(w, " %9d", c)
+	}
+	fmt.Fprintf(w, " %9d\n\n", len(seen))
+}
+
+// failedErr returns an error naming the queries that failed, or nil if none did
+func failedErr(results []codeResult) error {
+	var failed []string
+	for _, r := range results {
+		if r.failed() {
+			failed = append(failed, r.query.Text)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d queries failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+}
+
+// printFailures prints a table of the queries that failed, one row per provider
+func printFailures(w io.Writer, results []codeResult) {
+	var rows [][4]string
+	for _, r := range results {
+		for _, f := range r.failures {
+			status := "-"
+			if code := httplib.StatusCode(f.err); code != 0 {
+				status = strconv.Itoa(code)
 			}
+			rows = append(rows, [4]string{r.query.Text, f.source, status, f.err.Error()})
 		}
-		results = append(results, result)
+	}
+	if len(rows) == 0 {
+		return
+	}
 
-		// Update line with completed result
-		lineParts = []string{}
-		lineParts = append(lineParts, results...)
-		if i < len(codes)-1 && len(todo) > 0 {
-			lineParts = append(lineParts, todo)
+	widths := [3]int{len("Query"), len("Provider"), len("Status")}
+	for _, row := range rows {
+		for i := range widths {
+			widths[i] = max(widths[i], len(row[i]))
 		}
-		line = strings.Join(lineParts, ", ")
+	}
 
-		fmt.Printf(lineClear, line)
-		time.Sleep(200 * time.Millisecond) // small delay so change is visible
+	fmt.Fprintln(w, util.Paint(util.RED, fmt.Sprintf("%d failed:", len(rows))))
+	format := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%s\n", widths[0], widths[1], widths[2])
+	fmt.Fprintf(w, format, "Query", "Provider", "Status", "Error")
+	for _, row := range rows {
+		fmt.Fprintf(w, format, row[0], row[1], row[2], row[3])
 	}
+	fmt.Fprintln(w)
+}
 
-	fmt.Println("\n")
-	util.PrintNumbers("VIP Numbers found:", httplib.DeduplicateAndSort(allVIP))
-	util.PrintNumbers("\nPlatinum Numbers found:", httplib.DeduplicateAndSort(allPlatinum))
-	util.PrintNumbers("\nNotable pattern matches found:", httplib.DeduplicateAndSort(allNumbers))
-	fmt.Println("")
+// printTimeouts warns about patterns that ran out of time, listing the numbers
+// they were treated as not matching
+func printTimeouts(w io.Writer, hits tierHits) {
+	if len(hits.timeouts) == 0 {
+		return
+	}
+
+	type pattern struct{ tier, regex string }
+	var order []pattern
+	numbers := make(map[pattern][]string)
+	for _, n := range slices.Sorted(maps.Keys(hits.timeouts)) {
+		for _, m := range hits.timeouts[n] {
+			p := pattern{m.Tier, m.Regex}
+			if numbers[p] == nil {
+				order = append(order, p)
+			}
+			numbers[p] = append(numbers[p], n)
+		}
+	}
+
+	fmt.Fprintln(w, util.Paint(util.RED, fmt.Sprintf("%d pattern(s) timed out after %s and were treated as not matching, adding nothing to scores:",
+		len(order), config.MatchTimeout)))
+	for _, p := range order {
+		nums := numbers[p]
+		shown := strings.Join(nums[:min(len(nums), 3)], ", ")
+		if len(nums) > 3 {
+			shown += ", ..."
+		}
+		fmt.Fprintf(w, "  %s '%s' on %d number(s): %s\n", p.tier, p.regex, len(nums), shown)
+	}
+	fmt.Fprint(w, "Run 'milk numbers patterns lint' to find patterns that backtrack heavily\n\n")
+}
+
+// areaKey returns the area code of a NANP number, or +<country code> for others
+func areaKey(e164 string) string {
+	n, ok := phone.Split(e164)
+	switch {
+	case !ok:
+		return e164
+	case n.IsNANP():
+		return n.National[:3]
+	default:
+		return "+" + n.Country
+	}
+}
+
+// entries pairs a tier's numbers with their scores, providers, locations and the
+// spans and names of the tier's patterns they matched, for printing
+func (h tierHits) entries(tier string, nums []string) []util.Entry {
+	entries := make([]util.Entry, len(nums))
+	for i, n := range nums {
+		e := util.Entry{
+			Number:     n,
+			Score:      h.scores[n],
+			Sources:    h.providers[n],
+			Location:   h.info[n].Location(),
+			RateCenter: h.info[n].RateCenter,
+			Price:      h.info[n].Price,
+			Details:    h.info[n].Details(),
+		}
+		for _, m := range h.matches[n] {
+			if m.Tier != tier {
+				continue
+			}
+			start, end := m.Highlight()
+			e.Highlights = append(e.Highlights, [2]int{start, end})
+			e.Patterns = append(e.Patterns, m.Name())
+			if m.IsWord() {
+				e.Word = vanity.Spell(phone.NationalOf(n), m.Label)
+			}
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+// notifyMatches lists every hit once, with its tiers ordered best first
+func (h tierHits) notifyMatches() []notify.Match {
+	var order []string
+	tiers := make(map[string][]string)
+	add := func(tier string, nums []string) {
+		for _, n := range nums {
+			if _, ok := tiers[n]; !ok {
+				order = append(order, n)
+			}
+			tiers[n] = append(tiers[n], tier)
+		}
+	}
+	for _, t := range h.tiers {
+		add(t.tier.Name, t.numbers)
+	}
+
+	matches := make([]notify.Match, 0, len(order))
+	for _, n := range order {
+		matches = append(matches, notify.NewMatch(n, h.scores[n], tiers[n], h.providers[n]))
+	}
+	return matches
+}
+
+// sendNotifications passes hits to the configured notifiers, warning on failure
+func sendNotifications(d *notify.Dispatcher, hits tierHits) {
+	if err := d.Notify(hits.notifyMatches()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// contains reports whether s is in list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,132 @@
+package numbers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// record is one number in machine-readable output
+type record struct {
+	Number      string            `json:"number"`       // national significant number, ex. 2125551234
+	E164        string            `json:"e164"`         // ex. +12125551234
+	CountryCode string            `json:"country_code"` // calling code, ex. 1
+	AreaCode    string            `json:"area_code"`    // first 3 digits of NANP numbers, empty for others
+	Locality    string            `json:"locality"`     // city or town given by the provider, ex. Brooklyn
+	Region      string            `json:"region"`       // state or province given by the provider, ex. NY
+	RateCenter  string            `json:"rate_center"`  // rate center given by the provider
+	Price       string            `json:"price"`        // price asked by the provider, ex. $5.00
+	Extra       map[string]string `json:"extra"`        // other details given by the provider, ex. lata
+	details     []string          // Extra as sorted key=value pairs, for CSV
+	Score       float64           `json:"score"`
+	Queries     []string          `json:"queries"`   // queries that found the number
+	Providers   []string          `json:"providers"` // providers that offered the number
+	Tiers       []string          `json:"tiers"`     // tiers the number is listed under, best first
+	Patterns    []string          `json:"patterns"`  // names of the patterns it matched in those tiers
+}
+
+// records lists every hit once, in the order they are printed
+func (h tierHits) records() []record {
+	var order []string
+	byNumber := make(map[string]*record)
+	for _, t := range h.tiers {
+		for _, e := range h.entries(t.tier.Name, t.numbers) {
+			r, ok := byNumber[e.Number]
+			if !ok {
+				n, _ := phone.Split(e.Number)
+				info := h.info[e.Number]
+				r = &record{
+					Number:      n.National,
+					E164:        e.Number,
+					CountryCode: n.Country,
+					Locality:    info.Locality,
+					Region:      info.Region,
+					RateCenter:  info.RateCenter,
+					Price:       info.Price,
+					Extra:       info.Extra,
+					details:     info.Details(),
+					Score:       e.Score,
+					Queries:     nonNil(h.queries[e.Number]),
+					Providers:   nonNil(e.Sources),
+					Tiers:       []string{},
+					Patterns:    []string{},
+				}
+				if n.IsNANP() {
+					r.AreaCode = n.National[:3]
+				}
+				if r.Extra == nil {
+					r.Extra = map[string]string{}
+				}
+				byNumber[e.Number] = r
+				order = append(order, e.Number)
+			}
+			r.Tiers = append(r.Tiers, t.tier.Name)
+			r.Patterns = append(r.Patterns, e.Patterns...)
+		}
+	}
+
+	records := make([]record, len(order))
+	for i, n := range order {
+		records[i] = *byNumber[n]
+	}
+	return records
+}
+
+// writeRecords writes records to w as json, csv or ndjson
+func writeRecords(w io.Writer, format string, records []record) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if records == nil {
+			records = []record{}
+		}
+		return enc.Encode(records)
+
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"number", "e164", "country_code", "area_code", "locality", "region", "rate_center", "price", "extra", "score", "queries", "providers", "tiers", "patterns"})
+		for _, r := range records {
+			cw.Write([]string{
+				r.Number,
+				r.E164,
+				r.CountryCode,
+				r.AreaCode,
+				r.Locality,
+				r.Region,
+				r.RateCenter,
+				r.Price,
+				strings.Join(r.details, ";"),
+				strconv.FormatFloat(r.Score, 'f', 1, 64),
+				strings.Join(r.Queries, ";"),
+				strings.Join(r.Providers, ";"),
+				strings.Join(r.Tiers, ";"),
+				strings.Join(r.Patterns, ";"),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// nonNil returns list, or an empty list if it is nil, so JSON shows [] rather than null
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
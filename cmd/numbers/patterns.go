@@ -0,0 +1,200 @@
+package numbers
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/milktart/milk/pkg/config"
+	httplib "github.com/milktart/milk/pkg/http"
+	"github.com/milktart/milk/pkg/phone"
+	"github.com/milktart/milk/pkg/score"
+	"github.com/milktart/milk/pkg/util"
+)
+
+//go:embed sample.txt
+var sampleNumbers []byte
+
+// executePatterns runs the pattern authoring subcommands
+func (h *Handler) executePatterns(args []string) error {
+	usage := func() {
+		fmt.Print("Usage: milk numbers patterns <subcommand> [options]\n\n")
+		fmt.Println("Subcommands:")
+		fmt.Println("  test       Try a candidate pattern against a corpus of numbers")
+		fmt.Println("  lint       Check the configured patterns for duplicates, missing anchors, shadowing and heavy backtracking")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("no patterns subcommand given")
+	}
+
+	switch args[0] {
+	case "test":
+		return h.executePatternsTest(args[1:])
+	case "lint":
+		return h.executePatternsLint(args[1:])
+	case "-h", "--help", "help":
+		usage()
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown patterns subcommand '%s'", args[0])
+	}
+}
+
+// executePatternsTest reports how a candidate pattern behaves on a corpus
+func (h *Handler) executePatternsTest(args []string) error {
+	fs := flag.NewFlagSet("numbers patterns test", flag.ExitOnError)
+	corpusFlag := fs.String("corpus", "sample", "Numbers to test against: sample, random, or a file path")
+	countFlag := fs.Int("n", 10000, "How many numbers to generate for --corpus random")
+	seedFlag := fs.Uint64("seed", 1, "Seed for --corpus random")
+	examplesFlag := fs.Int("examples", 10, "How many example matches to show")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: milk numbers patterns test [options] <pattern>\n\n")
+		fmt.Print("Run a candidate regexp2 pattern against a corpus of numbers and report its\n")
+		fmt.Print("hit rate, example matches, overlap with the configured tiers and match time.\n\n")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println("\nExamples:")
+		fmt.Println("  milk numbers patterns test '(\\d)\\1{5}$'")
+		fmt.Println("  milk numbers patterns test --corpus random -n 100000 '.*(\\d{2})\\1\\1$'")
+		fmt.Println("  milk numbers patterns test --corpus numbers.txt '^212'")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupColor(false)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one pattern, got %d", fs.NArg())
+	}
+	if *examplesFlag < 0 {
+		return fmt.Errorf("--examples must not be negative, got %d", *examplesFlag)
+	}
+
+	pattern := fs.Arg(0)
+	re, err := config.CompileRegex(pattern)
+	if err != nil {
+		return fmt.Errorf("failed to compile pattern '%s': %w", pattern, err)
+	}
+	candidate := config.CompiledPattern{Pattern: config.Pattern{Regex: pattern}, Re: re}
+
+	corpus, desc, err := loadCorpus(*corpusFlag, *countFlag, *seedFlag)
+	if err != nil {
+		return err
+	}
+	if len(corpus) == 0 {
+		return fmt.Errorf("corpus %s contains no numbers", desc)
+	}
+
+	var hits []util.Entry
+	var total, slowest time.Duration
+	timeouts := 0
+	for _, n := range corpus {
+		start := time.Now()
+		m, ok, err := httplib.MatchPattern(phone.NationalOf(n), "", candidate)
+		elapsed := time.Since(start)
+		if err != nil {
+			timeouts++
+		}
+
+		total += elapsed
+		if elapsed > slowest {
+			slowest = elapsed
+		}
+		if ok {
+			s, e := m.Highlight()
+			hits = append(hits, util.Entry{
+				Number:     n,
+				Highlights: [][2]int{{s, e}},
+			})
+		}
+	}
+
+	fmt.Printf("Pattern:  %s\n", pattern)
+	fmt.Printf("Corpus:   %s (%d numbers)\n", desc, len(corpus))
+	fmt.Printf("Hits:     %d (%.2f%%)\n", len(hits), 100*float64(len(hits))/float64(len(corpus)))
+	fmt.Printf("Timing:   total %s, avg %s, max %s per number\n\n",
+		total.Round(time.Microsecond), (total / time.Duration(len(corpus))).Round(time.Nanosecond), slowest)
+	if timeouts > 0 {
+		fmt.Println(util.Paint(util.RED, fmt.Sprintf("Timeouts: %d numbers took longer than %s and were treated as not matching\n",
+			timeouts, config.MatchTimeout)))
+	}
+
+	if len(hits) == 0 {
+		return nil
+	}
+
+	nums := make([]string, len(hits))
+	for i, e := range hits {
+		nums[i] = e.Number
+	}
+	c := httplib.ClassifyNumbers(nums, h.cfg.CompiledTiers, h.cfg.CompiledWords)
+	for i := range hits {
+		hits[i].Score = score.Score(hits[i].Number, h.cfg, matchedBy(c.Matches[hits[i].Number]))
+	}
+
+	examples := hits
+	if len(examples) > *examplesFlag {
+		examples = examples[:*examplesFlag]
+	}
+	util.PrintNumbers(fmt.Sprintf("Examples (%d of %d):", len(examples), len(hits)), util.YELLOW, examples, nil, false)
+
+	unclassified := 0
+	for _, n := range nums {
+		if len(c.Matches[n]) == 0 {
+			unclassified++
+		}
+	}
+
+	fmt.Println("\nOverlap with existing tiers:")
+	overlap := func(name string, count int) {
+		fmt.Printf("  %-10s %6d of %d (%.1f%%)\n", name, count, len(hits), 100*float64(count)/float64(len(hits)))
+	}
+	for _, t := range h.cfg.CompiledTiers {
+		overlap(t.Name, len(c.Tiers[t.Name]))
+	}
+	overlap("(none)", unclassified)
+	fmt.Println("")
+	return nil
+}
+
+// loadCorpus returns the numbers to test against and a description of where they came from
+func loadCorpus(corpus string, count int, seed uint64) ([]string, string, error) {
+	switch corpus {
+	case "sample":
+		nums, err := scanNumbers(bytes.NewReader(sampleNumbers))
+		return nums, "built-in sample", err
+	case "random":
+		if count < 1 {
+			return nil, "", fmt.Errorf("-n must be positive, got %d", count)
+		}
+		return randomNumbers(count, seed), fmt.Sprintf("random, seed %d", seed), nil
+	default:
+		f, err := os.Open(corpus)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		nums, err := scanNumbers(f)
+		return nums, corpus, err
+	}
+}
+
+// randomNumbers generates count valid NANP numbers (+1 NXX-NXX-XXXX)
+func randomNumbers(count int, seed uint64) []string {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	nums := make([]string, count)
+	for i := range nums {
+		nums[i] = fmt.Sprintf("+1%d%02d%d%02d%04d",
+			2+rng.IntN(8), rng.IntN(100), 2+rng.IntN(8), rng.IntN(100), rng.IntN(10000))
+	}
+	return nums
+}
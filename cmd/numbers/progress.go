@@ -0,0 +1,169 @@
+package numbers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/milktart/milk/pkg/query"
+	"github.com/milktart/milk/pkg/util"
+	"golang.org/x/term"
+)
+
+// codeState tracks where a query is in the search
+type codeState int
+
+const (
+	statePending codeState = iota
+	stateRunning
+	stateDone
+	stateFailed
+)
+
+// progressStyle selects how search progress is shown
+type progressStyle int
+
+const (
+	progressLine progressStyle = iota // one colored line redrawn in place, for terminals
+	progressLog                       // one plain line per finished query, for pipes and logs
+	progressNone                      // nothing, for --quiet
+)
+
+// chooseProgress picks the progress style for out
+func chooseProgress(quiet bool, out *os.File) progressStyle {
+	switch {
+	case quiet:
+		return progressNone
+	case util.ColorEnabled() && isTerminal(out):
+		return progressLine
+	default:
+		return progressLog
+	}
+}
+
+// setupColor turns color off when noColor is set, NO_COLOR is set, or stdout is not a terminal
+func setupColor(noColor bool) {
+	util.SetColor(!noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout))
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// progress renders the done/current/todo status of a search
+type progress struct {
+	mu      sync.Mutex
+	out     *os.File
+	style   progressStyle
+	queries []query.Query
+	states  []codeState
+}
+
+// newProgress creates a progress display on out for the given queries, all pending
+func newProgress(out *os.File, style progressStyle, queries []query.Query) *progress {
+	return &progress{
+		out:     out,
+		style:   style,
+		queries: queries,
+		states:  make([]codeState, len(queries)),
+	}
+}
+
+// Begin prints the header and the initial status
+func (p *progress) Begin(header string) {
+	if p.style == progressNone {
+		return
+	}
+	fmt.Fprintln(p.out, header)
+	if p.style == progressLine {
+		p.mu.Lock()
+		p.draw()
+		p.mu.Unlock()
+	}
+}
+
+// End finishes the status so results can be printed below it
+func (p *progress) End() {
+	switch p.style {
+	case progressLine:
+		fmt.Fprint(p.out, "\n\n")
+	case progressLog:
+		fmt.Fprintln(p.out)
+	}
+}
+
+// Start marks query i as in flight and redraws the line
+func (p *progress) Start(i int) {
+	p.set(i, stateRunning)
+}
+
+// Finish marks query i as completed or failed and redraws the line
+func (p *progress) Finish(i int, ok bool) {
+	if ok {
+		p.set(i, stateDone)
+	} else {
+		p.set(i, stateFailed)
+	}
+}
+
+func (p *progress) set(i int, s codeState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.states[i] = s
+	switch p.style {
+	case progressLine:
+		p.draw()
+	case progressLog:
+		p.log(i)
+	}
+}
+
+// log prints a line for query i once it has finished
+func (p *progress) log(i int) {
+	q := p.queries[i]
+	switch p.states[i] {
+	case stateDone:
+		fmt.Fprintf(p.out, "  %s (%s): done\n", q.Text, q.Kind)
+	case stateFailed:
+		fmt.Fprintf(p.out, "  %s (%s): failed\n", q.Text, q.Kind)
+	}
+}
+
+// draw prints the queries grouped by type, in their original order and colored by state
+func (p *progress) draw() {
+	var groups []string
+	for _, kind := range []query.Kind{query.AreaCode, query.Exchange, query.Contains, query.Partial} {
+		var parts []string
+		for i, q := range p.queries {
+			if q.Kind != kind {
+				continue
+			}
+			switch p.states[i] {
+			case statePending:
+				parts = append(parts, util.BLUE+q.Text+util.NC)
+			case stateRunning:
+				parts = append(parts, util.BLUEBLINK+q.Text+util.NC)
+			case stateDone:
+				parts = append(parts, util.GREEN+q.Text+util.NC)
+			case stateFailed:
+				parts = append(parts, util.RED+q.Text+util.NC)
+			}
+		}
+		if len(parts) > 0 {
+			groups = append(groups, kind.String()+": "+strings.Join(parts, ", "))
+		}
+	}
+	line := strings.Join(groups, "; ")
+
+	// Move up a line first if the previous draw wrapped; without a width, assume it did not
+	lineClear := "\r  %s"
+	if width, _, err := term.GetSize(int(p.out.Fd())); err == nil && width > 0 &&
+		utf8.RuneCountInString(util.StripANSI(line)) >= width {
+		lineClear = "\r\033[A  %s"
+	}
+
+	fmt.Fprintf(p.out, lineClear, line)
+}
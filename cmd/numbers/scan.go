@@ -0,0 +1,99 @@
+package numbers
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	httplib "github.com/milktart/milk/pkg/http"
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// candidateRE finds international numbers starting with + and NANP numbers
+// written with optional country code, parentheses and separators
+var candidateRE = regexp.MustCompile(`\+\d(?:[\s.()-]?\d){6,14}|(?:1[\s.-]*)?\(?\d{3}\)?[\s.-]*\d{3}[\s.-]*\d{4}`)
+
+// executeScan classifies numbers read from a file or stdin without touching the network
+func (h *Handler) executeScan(args []string) error {
+	fs := flag.NewFlagSet("numbers scan", flag.ExitOnError)
+	patternFlag := fs.String("p", "", "Tier(s) to report, as named in patterns.yaml (ex. -p VIP,platinum)")
+	fs.StringVar(patternFlag, "pattern", "", "Same as -p")
+	minScoreFlag := fs.Float64("min-score", 0, "Only report numbers scoring at least this much")
+	display := addDisplayFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: milk numbers scan [options] [file|-]\n\n")
+		fmt.Print("Classify phone numbers from a file or stdin using the configured patterns.\n")
+		fmt.Print("Numbers may be in any common format, with a leading + for non-NANP numbers;\n")
+		fmt.Print("the network is not used.\n\n")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println("\nExamples:")
+		fmt.Println("  milk numbers scan numbers.txt")
+		fmt.Println("  pbpaste | milk numbers scan -p VIP -")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := display.setup(); err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		if name := fs.Arg(0); name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected at most one file, got %d", fs.NArg())
+	}
+
+	tiers, err := selectTiers(h.cfg, *patternFlag)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := scanNumbers(in)
+	if err != nil {
+		return err
+	}
+
+	c := httplib.ClassifyNumbers(candidates, h.cfg.CompiledTiers, h.cfg.CompiledWords)
+
+	fmt.Fprintf(display.log(), "Scanned %d numbers\n\n", len(candidates))
+	found := []sourceResult{{Classification: c}}
+	hits := mergeFound(found, tiers, filter{minScore: *minScoreFlag}, display.allTiers)
+	printHits(hits, *display)
+	printSummary(display.log(), hits)
+	printTimeouts(display.log(), hits)
+	return nil
+}
+
+// scanNumbers extracts every number found in r, normalized to E.164
+func scanNumbers(r io.Reader) ([]string, error) {
+	var nums []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		for _, c := range candidateRE.FindAllString(scanner.Text(), -1) {
+			if n, ok := phone.Normalize(c); ok {
+				nums = append(nums, n)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return httplib.DeduplicateAndSort(nums), nil
+}
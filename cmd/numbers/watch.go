@@ -0,0 +1,203 @@
+package numbers
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// ExitNewNumbers is the exit status used when watch --exit-on-new finds new numbers
+const ExitNewNumbers = 3
+
+// ErrNewNumbers is returned by watch --exit-on-new once a poll finds new numbers
+var ErrNewNumbers = errors.New("new numbers found")
+
+// watchState records the numbers already reported, per tier
+type watchState struct {
+	Seen map[string]map[string]time.Time `json:"seen"` // lowercased tier name -> number -> first seen
+}
+
+// executeWatch repeats a search on an interval and reports only numbers not seen before
+func (h *Handler) executeWatch(args []string) error {
+	fs := flag.NewFlagSet("numbers watch", flag.ExitOnError)
+	sf := addSearchFlags(fs)
+	display := addDisplayFlags(fs)
+	intervalFlag := fs.Duration("interval", 15*time.Minute, "Time to wait between polls")
+	stateFlag := fs.String("state", defaultStatePath(), "File recording numbers already reported")
+	exitFlag := fs.Bool("exit-on-new", false, fmt.Sprintf("Exit with status %d as soon as a poll finds new numbers", ExitNewNumbers))
+	sf.addRegionFlags(fs, h.cfg)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: milk numbers watch [options]\n\n")
+		fmt.Print("Repeat a numbers search on an interval and report only newly available numbers.\n")
+		fmt.Println("Queries that fail to fetch are retried on the next poll.")
+		fmt.Println("New numbers are also sent to any notifiers configured in notify.yaml.")
+		fmt.Print("For machine-readable output use --output ndjson, one record per line; json is not supported.\n\n")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+		fmt.Println()
+		printQuerySyntax()
+		fmt.Println()
+		printRegions(h.cfg, sf)
+		fmt.Println("\nExamples:")
+		fmt.Println("  milk numbers watch --interval 15m -r NYC -p VIP")
+		fmt.Println("  milk numbers watch --exit-on-new -c 212,646; [ $? -eq 3 ] && say 'new numbers'")
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := display.setup(); err != nil {
+		return err
+	}
+	if display.output == outputJSON {
+		return fmt.Errorf("--output json would print a separate array each poll; use --output ndjson with watch")
+	}
+
+	srch, err := sf.resolve(h.cfg, positional)
+	if err != nil {
+		return err
+	}
+	if *intervalFlag <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", *intervalFlag)
+	}
+
+	state, err := loadWatchState(*stateFlag)
+	if err != nil {
+		return err
+	}
+
+	for {
+		header := fmt.Sprintf("[%s] Searching these area codes or patterns:", time.Now().Format(time.DateTime))
+		results := searchCodes(header, srch.queries, srch.opts, *display)
+
+		failed := 0
+		for _, r := range results {
+			if r.failed() {
+				failed++
+			}
+		}
+		if failed > 0 {
+			printFailures(display.log(), results)
+			fmt.Fprintf(display.log(), "%d of %d queries failed, retrying next poll\n\n", failed, len(results))
+		}
+
+		hits := mergeFound(collectFound(results), srch.tiers, srch.filter, display.allTiers)
+		printTimeouts(display.log(), hits)
+
+		fresh := state.filterNew(hits, time.Now())
+		count := fresh.count()
+		if count == 0 {
+			fmt.Fprint(display.log(), "No new numbers\n\n")
+		} else {
+			printHits(fresh, *display)
+			sendNotifications(srch.notifier, fresh)
+		}
+
+		if err := state.save(*stateFlag); err != nil {
+			return err
+		}
+		if *exitFlag && count > 0 {
+			return ErrNewNumbers
+		}
+
+		fmt.Fprintf(display.log(), "Next poll at %s\n\n", time.Now().Add(*intervalFlag).Format(time.TimeOnly))
+		time.Sleep(*intervalFlag)
+	}
+}
+
+// defaultStatePath returns $XDG_STATE_HOME/milk/watch.json, falling back to ~/.local/state
+func defaultStatePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "milk-watch.json"
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "milk", "watch.json")
+}
+
+// loadWatchState reads the state file, starting empty if it does not exist yet
+func loadWatchState(path string) (*watchState, error) {
+	state := &watchState{Seen: make(map[string]map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", path, err)
+	}
+	if state.Seen == nil {
+		state.Seen = make(map[string]map[string]time.Time)
+	}
+	// Older state files recorded NANP numbers as 10 digits rather than E.164
+	for _, seen := range state.Seen {
+		for n, t := range seen {
+			if e164, ok := phone.Normalize(n); ok && e164 != n {
+				delete(seen, n)
+				seen[e164] = t
+			}
+		}
+	}
+	return state, nil
+}
+
+// save writes the state file atomically
+func (s *watchState) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// filterNew returns the hits not seen before and records them as seen at now
+func (s *watchState) filterNew(hits tierHits, now time.Time) tierHits {
+	// The details of each number, such as its queries, are kept as they are
+	fresh := hits
+	fresh.tiers = nil
+	for _, t := range hits.tiers {
+		fresh.tiers = append(fresh.tiers, tierNumbers{
+			tier:    t.tier,
+			numbers: s.markSeen(strings.ToLower(t.tier.Name), t.numbers, now),
+		})
+	}
+	return fresh
+}
+
+// markSeen records numbers under tier and returns the ones that were new
+func (s *watchState) markSeen(tier string, numbers []string, now time.Time) []string {
+	seen := s.Seen[tier]
+	if seen == nil {
+		seen = make(map[string]time.Time)
+		s.Seen[tier] = seen
+	}
+	var fresh []string
+	for _, n := range numbers {
+		if _, ok := seen[n]; !ok {
+			seen[n] = now
+			fresh = append(fresh, n)
+		}
+	}
+	return fresh
+}
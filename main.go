@@ -1,6 +1,7 @@
 package main
 
 import (
+  "errors"
   "fmt"
   "os"
   "strings"
@@ -18,8 +19,11 @@ const (
 func printMainMenu() {
   fmt.Printf("%s - A multi-use CLI tool\n\n", TOOLNAME)
   fmt.Println("Usage:")
-  fmt.Printf("  %s <command> [options]\n", TOOLNAME)
+  fmt.Printf("  %s [--config <dir>] <command> [options]\n", TOOLNAME)
   fmt.Printf("  %s --help\n\n", TOOLNAME)
+  fmt.Printf("Patterns, regions and notifiers are read from %s\n", config.DefaultDir())
+  fmt.Println("(or --config <dir>) and merged over the built-in defaults.")
+  fmt.Println()
   fmt.Println("Commands:")
   fmt.Println("  numbers    Search for special phone numbers by area code and pattern")
   fmt.Println("  flights    Calculate flight distances between locations")
@@ -28,12 +32,60 @@ func printMainMenu() {
   fmt.Println("Examples:")
   fmt.Printf("  %s numbers -c 212 415 808 -r Canada -p VIP\n", TOOLNAME)
   fmt.Printf("  %s numbers --Canada\n", TOOLNAME)
+  fmt.Printf("  %s numbers scan numbers.txt\n", TOOLNAME)
+  fmt.Printf("  %s --config ./milk numbers config show\n", TOOLNAME)
   fmt.Printf("  %s flights -R SEA TPE\n", TOOLNAME)
   fmt.Printf("  %s flights AUS KL.Z AMS KL.Z HEL XX PRG KL.N AMS KL.Z AUS\n", TOOLNAME)
 }
 
 
+// extractConfigDir removes a --config <dir> or --config=<dir> option given
+// before the command from args, and returns the directory given or the default
+// one. Everything from the command on is passed through unchanged. Unlike the
+// default directory, a directory given explicitly must exist.
+func extractConfigDir(args []string) (string, []string, error) {
+  dir, explicit := config.DefaultDir(), false
+  rest := make([]string, 0, len(args))
+  i := 0
+  loop:
+  for ; i < len(args); i++ {
+    arg := args[i]
+    switch {
+      case arg == "--config" || arg == "-config":
+        if i+1 >= len(args) {
+          return "", nil, fmt.Errorf("--config requires a directory")
+        }
+        dir, explicit = args[i+1], true
+        i++
+      case strings.HasPrefix(arg, "--config=") || strings.HasPrefix(arg, "-config="):
+        dir, explicit = arg[strings.Index(arg, "=")+1:], true
+      case !strings.HasPrefix(arg, "-"):
+        break loop
+      default:
+        rest = append(rest, arg)
+    }
+  }
+
+  if explicit {
+    info, err := os.Stat(dir)
+    if err != nil {
+      return "", nil, fmt.Errorf("config directory: %w", err)
+    }
+    if !info.IsDir() {
+      return "", nil, fmt.Errorf("config directory %s is not a directory", dir)
+    }
+  }
+  return dir, append(rest, args[i:]...), nil
+}
+
 func main() {
+  configDir, args, err := extractConfigDir(os.Args[1:])
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+    os.Exit(1)
+  }
+  os.Args = append(os.Args[:1], args...)
+
   if len(os.Args) < 2 {
     printMainMenu()
     os.Exit(0)
@@ -50,13 +102,19 @@ func main() {
   // Route to subcommands
   switch strings.ToLower(subcommand) {
     case "numbers":
-      cfg, err := config.LoadFromBytes()
+      cfg, err := config.Load(configDir)
       if err != nil {
         fmt.Fprintf(os.Stderr, "Error: %v\n", err)
         os.Exit(1)
       }
+      for _, w := range cfg.Warnings {
+        fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+      }
       handler := numbers.NewHandler(cfg)
       if err := handler.Execute(os.Args[2:]); err != nil {
+        if errors.Is(err, numbers.ErrNewNumbers) {
+          os.Exit(numbers.ExitNewNumbers)
+        }
         fmt.Fprintf(os.Stderr, "Error: %v\n", err)
         os.Exit(1)
       }
@@ -3,10 +3,12 @@ package config
 import (
 	_ "embed"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/dlclark/regexp2"
+	"github.com/milktart/milk/pkg/util"
+	"github.com/milktart/milk/pkg/vanity"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,26 +18,119 @@ var (
 
 	//go:embed regions.yaml
 	regionsYAMLBytes []byte
+
+	//go:embed words.txt
+	wordsBytes []byte
 )
 
-// Note: This package loads configuration from YAML files in the config/ directory
+// Note: This package loads its defaults from the embedded YAML files, which mirror
+// the config/ directory, and merges the user's files from DefaultDir() over them
 
 // Config holds all configuration data
 type Config struct {
-	Patterns PatternsConfig `yaml:"patterns"`
-	Regions  RegionsConfig  `yaml:"regions"`
+	Tiers   []TierConfig  `yaml:"tiers"`
+	Scoring ScoringConfig `yaml:"scoring"`
+	Regions RegionsConfig `yaml:"regions"`
+
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+
+	// Words are matched on the keypad against the end of each number (from words.txt)
+	Words []string `yaml:"-"`
+
+	// Files holds the user files merged over the defaults (not in YAML)
+	Files []string `yaml:"-"`
+
+	// Warnings describes parts of the user files that had no effect (not in YAML)
+	Warnings []string `yaml:"-"`
 
 	// Compiled regexes (not in YAML)
-	CompiledVIP      []*regexp2.Regexp
-	CompiledPlatinum []*regexp2.Regexp
-	CompiledNotable  []*regexp2.Regexp
+	CompiledTiers []CompiledTier
+	CompiledWords *vanity.Dictionary // nil unless a tier has vanity set
+}
+
+// TierConfig is a named group of patterns; tiers are listed best first
+type TierConfig struct {
+	Name     string    `yaml:"name"`
+	Color    string    `yaml:"color,omitempty"`    // red, green, yellow, blue, magenta, cyan or white
+	Weight   float64   `yaml:"weight,omitempty"`   // default weight of the tier's patterns
+	Country  string    `yaml:"country,omitempty"`  // default country calling code of the tier's patterns
+	Vanity   bool      `yaml:"vanity,omitempty"`   // match the word list instead of (or as well as) patterns
+	Patterns []Pattern `yaml:"patterns,omitempty"` // regexes a number must match to be in the tier
+}
+
+// CompiledTier is a TierConfig with its patterns compiled and color resolved
+type CompiledTier struct {
+	Name     string
+	Color    string // ANSI code
+	Vanity   bool
+	Patterns []CompiledPattern
+}
+
+// Pattern is a regex along with the weight a match adds to a number's score.
+// It is matched against the national significant number, ex. 2125551234 for
+// +12125551234, of numbers from Country, or of every number if Country is empty.
+// In YAML it is either a bare regex string or a mapping with regex, weight, label and country.
+type Pattern struct {
+	Regex   string  `yaml:"regex"`
+	Weight  float64 `yaml:"weight,omitempty"`  // 0 means the tier's default weight
+	Label   string  `yaml:"label,omitempty"`   // human-readable name shown by --explain
+	Country string  `yaml:"country,omitempty"` // calling code, ex. 1 or 44; empty means the tier's
+}
+
+// UnmarshalYAML accepts either a bare regex string or a mapping
+func (p *Pattern) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&p.Regex)
+	}
+	type plain Pattern
+	return node.Decode((*plain)(p))
+}
+
+// MarshalYAML writes patterns without a weight or label as a bare regex string
+func (p Pattern) MarshalYAML() (any, error) {
+	if p.Weight == 0 && p.Label == "" && p.Country == "" {
+		return p.Regex, nil
+	}
+	type plain Pattern
+	return plain(p), nil
+}
+
+// MatchTimeout bounds the time a pattern may spend matching one number, so a
+// pattern that backtracks badly cannot hang a search
+const MatchTimeout = 100 * time.Millisecond
+
+// CompileRegex compiles a pattern regex with MatchTimeout set
+func CompileRegex(expr string) (*regexp2.Regexp, error) {
+	re, err := regexp2.Compile(expr, 0)
+	if err != nil {
+		return nil, err
+	}
+	re.MatchTimeout = MatchTimeout
+	return re, nil
+}
+
+// CompiledPattern is a Pattern with its regex compiled and weight and country resolved
+type CompiledPattern struct {
+	Pattern
+	Re *regexp2.Regexp
+}
+
+// AppliesTo reports whether the pattern is matched against numbers with the given calling code
+func (p CompiledPattern) AppliesTo(country string) bool {
+	return p.Country == "" || p.Country == country
 }
 
-// PatternsConfig holds regex patterns organized by tier
-type PatternsConfig struct {
-	VIP      []string `yaml:"vip"`
-	Platinum []string `yaml:"platinum"`
-	Notable  []string `yaml:"notable"`
+// defaultTierColor is used for tiers that do not name a color
+const defaultTierColor = "yellow"
+
+// ScoringConfig holds the weights used to score numbers.
+// A number's score is the sum of the weights of every pattern it matches,
+// plus the digit signals below computed over its last 7 digits.
+type ScoringConfig struct {
+	Entropy  float64 `yaml:"entropy"`  // awarded in full for a single repeated digit
+	Runs     float64 `yaml:"runs"`     // per digit of the longest repeated run beyond the first
+	Distinct float64 `yaml:"distinct"` // per distinct digit fewer than 7
+	Vanity   float64 `yaml:"vanity"`   // for spelling a 7-letter word, prorated for shorter ones
 }
 
 // RegionsConfig holds region code mappings
@@ -43,55 +138,58 @@ type RegionsConfig map[string][]string
 
 var cfg *Config
 
-// Load loads and parses configuration from YAML files
+// Load loads the embedded defaults and merges the user's overrides from configDir over them.
+// patterns.yaml, regions.yaml and notify.yaml in configDir are each optional.
 func Load(configDir string) (*Config, error) {
-	// Load patterns
-	patternsFile := filepath.Join(configDir, "patterns.yaml")
-	patternsData, err := os.ReadFile(patternsFile)
+	c, err := parseDefaults()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read patterns.yaml: %w", err)
+		return nil, err
 	}
 
-	var patternsYAML struct {
-		Patterns PatternsConfig `yaml:"patterns"`
+	if err := applyOverrides(c, configDir); err != nil {
+		return nil, err
 	}
 
-	if err := yaml.Unmarshal(patternsData, &patternsYAML); err != nil {
-		return nil, fmt.Errorf("failed to parse patterns.yaml: %w", err)
-	}
+	cfg = c
 
-	// Load regions
-	regionsFile := filepath.Join(configDir, "regions.yaml")
-	regionsData, err := os.ReadFile(regionsFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read regions.yaml: %w", err)
+	// Compile regexes
+	if err := compileRegexes(); err != nil {
+		return nil, err
 	}
 
-	var regionsYAML struct {
-		Regions RegionsConfig `yaml:"regions"`
+	if err := cfg.Regions.validate(); err != nil {
+		return nil, err
 	}
 
-	if err := yaml.Unmarshal(regionsData, &regionsYAML); err != nil {
-		return nil, fmt.Errorf("failed to parse regions.yaml: %w", err)
-	}
+	return cfg, nil
+}
 
-	cfg = &Config{
-		Patterns: patternsYAML.Patterns,
-		Regions:  regionsYAML.Regions,
+// LoadFromBytes loads and parses configuration from embedded YAML bytes
+func LoadFromBytes() (*Config, error) {
+	c, err := parseDefaults()
+	if err != nil {
+		return nil, err
 	}
 
+	cfg = c
+
 	// Compile regexes
 	if err := compileRegexes(); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.Regions.validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
-// LoadFromBytes loads and parses configuration from embedded YAML bytes
-func LoadFromBytes() (*Config, error) {
+// parseDefaults parses the embedded YAML files
+func parseDefaults() (*Config, error) {
 	var patternsYAML struct {
-		Patterns PatternsConfig `yaml:"patterns"`
+		Tiers   []TierConfig  `yaml:"tiers"`
+		Scoring ScoringConfig `yaml:"scoring"`
 	}
 
 	if err := yaml.Unmarshal(patternsYAMLBytes, &patternsYAML); err != nil {
@@ -106,46 +204,82 @@ func LoadFromBytes() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse regions.yaml: %w", err)
 	}
 
-	cfg = &Config{
-		Patterns: patternsYAML.Patterns,
-		Regions:  regionsYAML.Regions,
-	}
-
-	// Compile regexes
-	if err := compileRegexes(); err != nil {
+	notifiers, err := parseNotifiers(notifyYAMLBytes)
+	if err != nil {
 		return nil, err
 	}
 
-	return cfg, nil
+	return &Config{
+		Tiers:     patternsYAML.Tiers,
+		Scoring:   patternsYAML.Scoring,
+		Regions:   regionsYAML.Regions,
+		Notifiers: notifiers,
+		Words:     vanity.ParseWords(wordsBytes),
+	}, nil
 }
 
 // compileRegexes compiles all regex patterns
 func compileRegexes() error {
-	for _, p := range cfg.Patterns.VIP {
-		re, err := regexp2.Compile(p, 0)
-		if err != nil {
-			return fmt.Errorf("failed to compile VIP pattern '%s': %w", p, err)
+	seen := make(map[string]bool, len(cfg.Tiers))
+	cfg.CompiledTiers = nil
+	cfg.CompiledWords = nil
+
+	for _, t := range cfg.Tiers {
+		if t.Name == "" {
+			return fmt.Errorf("every tier needs a name")
 		}
-		cfg.CompiledVIP = append(cfg.CompiledVIP, re)
-	}
+		if seen[strings.ToLower(t.Name)] {
+			return fmt.Errorf("tier '%s' is defined twice", t.Name)
+		}
+		seen[strings.ToLower(t.Name)] = true
 
-	for _, p := range cfg.Patterns.Platinum {
-		re, err := regexp2.Compile(p, 0)
+		colorName := t.Color
+		if colorName == "" {
+			colorName = defaultTierColor
+		}
+		color, ok := util.ColorByName(colorName)
+		if !ok {
+			return fmt.Errorf("tier '%s' has unknown color '%s'", t.Name, t.Color)
+		}
+
+		patterns, err := compileTier(t.Name, t.Patterns, t.Weight, t.Country)
 		if err != nil {
-			return fmt.Errorf("failed to compile Platinum pattern '%s': %w", p, err)
+			return err
 		}
-		cfg.CompiledPlatinum = append(cfg.CompiledPlatinum, re)
+
+		if t.Vanity && cfg.CompiledWords == nil {
+			cfg.CompiledWords = vanity.NewDictionary(cfg.Words)
+		}
+
+		cfg.CompiledTiers = append(cfg.CompiledTiers, CompiledTier{
+			Name:     t.Name,
+			Color:    color,
+			Vanity:   t.Vanity,
+			Patterns: patterns,
+		})
 	}
+	return nil
+}
 
-	for _, p := range cfg.Patterns.Notable {
-		re, err := regexp2.Compile(p, 0)
+// compileTier compiles a tier's patterns, giving unweighted ones the tier's default
+// weight and ones without a country the tier's country
+func compileTier(tier string, patterns []Pattern, defaultWeight float64, defaultCountry string) ([]CompiledPattern, error) {
+	compiled := make([]CompiledPattern, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := CompileRegex(p.Regex)
 		if err != nil {
-			return fmt.Errorf("failed to compile Notable pattern '%s': %w", p, err)
+			return nil, fmt.Errorf("failed to compile %s pattern '%s': %w", tier, p.Regex, err)
+		}
+		if p.Weight == 0 {
+			p.Weight = defaultWeight
 		}
-		cfg.CompiledNotable = append(cfg.CompiledNotable, re)
+		if p.Country == "" {
+			p.Country = defaultCountry
+		}
+		p.Country = strings.TrimPrefix(p.Country, "+")
+		compiled = append(compiled, CompiledPattern{Pattern: p, Re: re})
 	}
-
-	return nil
+	return compiled, nil
 }
 
 // Get returns the current config instance
@@ -153,10 +287,31 @@ func Get() *Config {
 	return cfg
 }
 
-// GetRegionCodes retrieves area codes for a region
-func (c *Config) GetRegionCodes(region string) []string {
-	if codes, ok := c.Regions[region]; ok {
-		return codes
+// Tier returns the compiled tier with the given name, ignoring case
+func (c *Config) Tier(name string) (CompiledTier, bool) {
+	for _, t := range c.CompiledTiers {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
 	}
-	return nil
+	return CompiledTier{}, false
+}
+
+// TierNames returns the names of the tiers, best first
+func (c *Config) TierNames() []string {
+	names := make([]string, len(c.CompiledTiers))
+	for i, t := range c.CompiledTiers {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// RegionNames returns the names of the configured regions, sorted
+func (c *Config) RegionNames() []string {
+	return c.Regions.Names()
+}
+
+// GetRegionCodes evaluates a region expression such as Canada+TX-416 to its area codes
+func (c *Config) GetRegionCodes(region string) ([]string, error) {
+	return c.Regions.Eval(region)
 }
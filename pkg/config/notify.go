@@ -0,0 +1,56 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed notify.yaml
+var notifyYAMLBytes []byte
+
+// NotifierConfig describes a single notification sink
+type NotifierConfig struct {
+	Type  string   `yaml:"type"`  // webhook, slack, discord, smtp or command
+	Name  string   `yaml:"name"`  // optional label used in error messages
+	Tiers []string `yaml:"tiers"` // only notify for these tiers (default: all)
+
+	// webhook, slack and discord
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	// smtp
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+
+	// command
+	Command []string `yaml:"command"`
+}
+
+// parseNotifiers parses the notifiers list from notify.yaml contents
+func parseNotifiers(data []byte) ([]NotifierConfig, error) {
+	var notifyYAML struct {
+		Notifiers []NotifierConfig `yaml:"notifiers"`
+	}
+
+	if err := yaml.Unmarshal(data, &notifyYAML); err != nil {
+		return nil, fmt.Errorf("failed to parse notify.yaml: %w", err)
+	}
+
+	return notifyYAML.Notifiers, nil
+}
+
+// LoadNotifiers reads notifier settings from a notify.yaml file
+func LoadNotifiers(path string) ([]NotifierConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseNotifiers(data)
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/milktart/milk/pkg/vanity"
+	"gopkg.in/yaml.v3"
+)
+
+// ListOverride describes how a user file changes a list from the defaults.
+// In YAML it is either a plain list, which replaces the defaults, or a mapping:
+//
+//	add:     [...]  # appended to the defaults
+//	remove:  [...]  # dropped from the defaults
+//	replace: [...]  # used instead of the defaults
+//	delete:  true   # regions only: remove the region entirely
+type ListOverride[T any] struct {
+	Add     []T  `yaml:"add"`
+	Remove  []T  `yaml:"remove"`
+	Replace []T  `yaml:"replace"`
+	Delete  bool `yaml:"delete"`
+
+	replaces bool // Replace was given, possibly as an empty list
+}
+
+// UnmarshalYAML accepts either a plain list or an add/remove/replace mapping
+func (o *ListOverride[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		o.replaces = true
+		return node.Decode(&o.Replace)
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a list or a mapping with add, remove or replace", node.Line)
+	}
+
+	type plain ListOverride[T]
+	if err := node.Decode((*plain)(o)); err != nil {
+		return err
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		switch key := node.Content[i].Value; key {
+		case "replace":
+			o.replaces = true
+		case "add", "remove", "delete":
+		default:
+			return fmt.Errorf("line %d: unknown key '%s' (expected add, remove, replace or delete)", node.Content[i].Line, key)
+		}
+	}
+	return nil
+}
+
+// apply returns base changed by the override, along with the keys of remove
+// entries that matched nothing; key identifies items for remove
+func (o ListOverride[T]) apply(base []T, key func(T) string) ([]T, []string) {
+	out := base
+	if o.replaces {
+		out = o.Replace
+	}
+
+	var unmatched []string
+	if len(o.Remove) > 0 {
+		drop := make(map[string]bool, len(o.Remove))
+		for _, r := range o.Remove {
+			drop[key(r)] = false
+		}
+		var kept []T
+		for _, item := range out {
+			if _, ok := drop[key(item)]; ok {
+				drop[key(item)] = true
+				continue
+			}
+			kept = append(kept, item)
+		}
+		out = kept
+		for _, r := range o.Remove {
+			if k := key(r); !drop[k] && !slices.Contains(unmatched, k) {
+				unmatched = append(unmatched, k)
+			}
+		}
+	}
+
+	return append(append([]T(nil), out...), o.Add...), unmatched
+}
+
+// DefaultDir returns the directory user configuration is read from:
+// $XDG_CONFIG_HOME/milk, falling back to ~/.config/milk
+func DefaultDir() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "milk")
+}
+
+// applyOverrides merges the user files found in dir into c.
+// Words in words.txt are added to the built-in list.
+func applyOverrides(c *Config, dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	if data, path, err := readOptional(dir, "patterns.yaml"); err != nil {
+		return err
+	} else if data != nil {
+		warnings, err := applyPatternsOverride(c, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		c.addWarnings(path, warnings)
+		c.Files = append(c.Files, path)
+	}
+
+	if data, path, err := readOptional(dir, "regions.yaml"); err != nil {
+		return err
+	} else if data != nil {
+		warnings, err := applyRegionsOverride(c, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		c.addWarnings(path, warnings)
+		c.Files = append(c.Files, path)
+	}
+
+	if data, path, err := readOptional(dir, "words.txt"); err != nil {
+		return err
+	} else if data != nil {
+		c.Words = append(c.Words, vanity.ParseWords(data)...)
+		c.Files = append(c.Files, path)
+	}
+
+	if data, path, err := readOptional(dir, "notify.yaml"); err != nil {
+		return err
+	} else if data != nil {
+		notifiers, err := parseNotifiers(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		c.Notifiers = notifiers
+		c.Files = append(c.Files, path)
+	}
+
+	return nil
+}
+
+// addWarnings records warnings about the user file at path
+func (c *Config) addWarnings(path string, warnings []string) {
+	for _, w := range warnings {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %s", path, w))
+	}
+}
+
+// unmatchedWarnings describes remove entries that matched nothing in what
+func unmatchedWarnings(what string, unmatched []string) []string {
+	var warnings []string
+	for _, key := range unmatched {
+		warnings = append(warnings, fmt.Sprintf("%s: remove '%s' matches nothing; see the current defaults with 'milk numbers config show'", what, key))
+	}
+	return warnings
+}
+
+// readOptional reads dir/name, returning nil data if the file does not exist
+func readOptional(dir, name string) ([]byte, string, error) {
+	path := filepath.Join(dir, name)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, path, nil
+}
+
+// tierOverride changes, adds or deletes a tier in a user patterns.yaml.
+// A name not among the defaults adds a new tier at the end, or before the tier named in before.
+type tierOverride struct {
+	Name     string                 `yaml:"name"`
+	Color    string                 `yaml:"color"`
+	Weight   float64                `yaml:"weight"`
+	Country  string                 `yaml:"country"`
+	Vanity   *bool                  `yaml:"vanity"`
+	Patterns *ListOverride[Pattern] `yaml:"patterns"`
+	Delete   bool                   `yaml:"delete"`
+	Before   string                 `yaml:"before"`
+}
+
+// applyPatternsOverride merges a user patterns.yaml into c, returning warnings
+// about remove entries that matched no pattern, such as a default regex that has
+// since been rewritten. Entries under tiers add, change or delete whole tiers;
+// entries under patterns change only the patterns of an existing tier. Both take
+// ListOverride values for patterns. Scoring settings replace the defaults field by field.
+func applyPatternsOverride(c *Config, data []byte) ([]string, error) {
+	var override struct {
+		Tiers    []tierOverride                   `yaml:"tiers"`
+		Patterns map[string]ListOverride[Pattern] `yaml:"patterns"`
+		Scoring  yaml.Node                        `yaml:"scoring"`
+	}
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return nil, fmt.Errorf("failed to parse patterns.yaml: %w", err)
+	}
+
+	var warnings []string
+	byRegex := func(p Pattern) string { return p.Regex }
+	for _, o := range override.Tiers {
+		if o.Name == "" {
+			return nil, fmt.Errorf("every tier needs a name")
+		}
+		i := tierIndex(c.Tiers, o.Name)
+
+		if o.Delete {
+			if i < 0 {
+				return nil, fmt.Errorf("cannot delete unknown tier '%s'", o.Name)
+			}
+			c.Tiers = append(c.Tiers[:i:i], c.Tiers[i+1:]...)
+			continue
+		}
+
+		var t TierConfig
+		if i >= 0 {
+			t = c.Tiers[i]
+		} else {
+			t.Name = o.Name
+		}
+		if o.Color != "" {
+			t.Color = o.Color
+		}
+		if o.Weight != 0 {
+			t.Weight = o.Weight
+		}
+		if o.Country != "" {
+			t.Country = o.Country
+		}
+		if o.Vanity != nil {
+			t.Vanity = *o.Vanity
+		}
+		if o.Patterns != nil {
+			var unmatched []string
+			t.Patterns, unmatched = o.Patterns.apply(t.Patterns, byRegex)
+			warnings = append(warnings, unmatchedWarnings("tier "+t.Name, unmatched)...)
+		}
+
+		if i >= 0 {
+			c.Tiers[i] = t
+			continue
+		}
+		at := len(c.Tiers)
+		if o.Before != "" {
+			if at = tierIndex(c.Tiers, o.Before); at < 0 {
+				return nil, fmt.Errorf("tier '%s' is to go before unknown tier '%s'", o.Name, o.Before)
+			}
+		}
+		c.Tiers = append(c.Tiers[:at], append([]TierConfig{t}, c.Tiers[at:]...)...)
+	}
+
+	for _, tier := range slices.Sorted(maps.Keys(override.Patterns)) {
+		i := tierIndex(c.Tiers, tier)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown tier '%s'", tier)
+		}
+		var unmatched []string
+		c.Tiers[i].Patterns, unmatched = override.Patterns[tier].apply(c.Tiers[i].Patterns, byRegex)
+		warnings = append(warnings, unmatchedWarnings("tier "+c.Tiers[i].Name, unmatched)...)
+	}
+
+	if !override.Scoring.IsZero() {
+		if err := override.Scoring.Decode(&c.Scoring); err != nil {
+			return nil, fmt.Errorf("failed to parse scoring: %w", err)
+		}
+	}
+	return warnings, nil
+}
+
+// tierIndex returns the position of the named tier, ignoring case, or -1
+func tierIndex(tiers []TierConfig, name string) int {
+	for i, t := range tiers {
+		if strings.EqualFold(t.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyRegionsOverride merges a user regions.yaml into c, returning warnings
+// about remove entries that matched no area code
+func applyRegionsOverride(c *Config, data []byte) ([]string, error) {
+	var override struct {
+		Regions map[string]ListOverride[string] `yaml:"regions"`
+	}
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return nil, fmt.Errorf("failed to parse regions.yaml: %w", err)
+	}
+
+	if c.Regions == nil {
+		c.Regions = make(RegionsConfig)
+	}
+	var warnings []string
+	same := func(code string) string { return code }
+	for _, region := range slices.Sorted(maps.Keys(override.Regions)) {
+		o := override.Regions[region]
+		if o.Delete {
+			delete(c.Regions, region)
+			continue
+		}
+		var unmatched []string
+		c.Regions[region], unmatched = o.apply(c.Regions[region], same)
+		warnings = append(warnings, unmatchedWarnings("region "+region, unmatched)...)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// mergeDefaults merges the named user files, given as contents, over the
+// embedded defaults in a temporary directory
+func mergeDefaults(t *testing.T, files map[string]string) (*Config, error) {
+	t.Helper()
+	c, err := parseDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c, applyOverrides(c, dir)
+}
+
+// regexes returns the regexes of the named tier's patterns, or nil if there is no such tier
+func regexes(c *Config, tier string) []string {
+	i := tierIndex(c.Tiers, tier)
+	if i < 0 {
+		return nil
+	}
+	var out []string
+	for _, p := range c.Tiers[i].Patterns {
+		out = append(out, p.Regex)
+	}
+	return out
+}
+
+func TestApplyPatternsOverride(t *testing.T) {
+	defaults, err := parseDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	platinum := regexes(defaults, "Platinum")
+
+	tests := []struct {
+		name  string
+		yaml  string
+		tier  string
+		want  []string
+		tiers []string // tier names, if the override changes them
+	}{
+		{
+			name: "add",
+			yaml: "patterns:\n  platinum:\n    add: ['1234$']\n",
+			tier: "Platinum",
+			want: append(append([]string(nil), platinum...), "1234$"),
+		},
+		{
+			name: "remove",
+			yaml: "patterns:\n  Platinum:\n    remove: ['(\\d{2})\\1[0]0$']\n",
+			tier: "Platinum",
+			want: []string{platinum[0], platinum[2]},
+		},
+		{
+			name: "replace",
+			yaml: "patterns:\n  Platinum:\n    replace: ['1234$']\n    add: ['5678$']\n",
+			tier: "Platinum",
+			want: []string{"1234$", "5678$"},
+		},
+		{
+			name: "plain list replaces",
+			yaml: "patterns:\n  Platinum: ['1234$']\n",
+			tier: "Platinum",
+			want: []string{"1234$"},
+		},
+		{
+			name: "empty replace",
+			yaml: "patterns:\n  Platinum:\n    replace: []\n",
+			tier: "Platinum",
+			want: nil,
+		},
+		{
+			name:  "delete tier",
+			yaml:  "tiers:\n  - name: platinum\n    delete: true\n",
+			tier:  "Platinum",
+			want:  nil,
+			tiers: []string{"VIP", "Notable", "Vanity"},
+		},
+		{
+			name:  "new tier",
+			yaml:  "tiers:\n  - name: Mine\n    before: Notable\n    patterns: ['1234$']\n",
+			tier:  "Mine",
+			want:  []string{"1234$"},
+			tiers: []string{"VIP", "Platinum", "Mine", "Notable", "Vanity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := mergeDefaults(t, map[string]string{"patterns.yaml": tt.yaml})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := regexes(c, tt.tier); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s patterns = %q, want %q", tt.tier, got, tt.want)
+			}
+			if tt.tiers != nil {
+				var names []string
+				for _, tier := range c.Tiers {
+					names = append(names, tier.Name)
+				}
+				if !reflect.DeepEqual(names, tt.tiers) {
+					t.Errorf("tiers = %q, want %q", names, tt.tiers)
+				}
+			}
+			if len(c.Files) != 1 || filepath.Base(c.Files[0]) != "patterns.yaml" {
+				t.Errorf("Files = %q, want the merged patterns.yaml", c.Files)
+			}
+			if len(c.Warnings) != 0 {
+				t.Errorf("Warnings = %q, want none", c.Warnings)
+			}
+		})
+	}
+}
+
+func TestApplyRegionsOverride(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		region string
+		want   []string
+	}{
+		{"add", "regions:\n  NYC:\n    add: ['516']\n", "NYC", []string{"212", "332", "347", "646", "718", "917", "929", "516"}},
+		{"remove", "regions:\n  NYC:\n    remove: ['212', '646']\n", "NYC", []string{"332", "347", "718", "917", "929"}},
+		{"replace", "regions:\n  NYC:\n    replace: ['212']\n", "NYC", []string{"212"}},
+		{"plain list replaces", "regions:\n  NYC: ['212', '646']\n", "NYC", []string{"212", "646"}},
+		{"new region", "regions:\n  Home: ['212', '718']\n", "Home", []string{"212", "718"}},
+		{"delete", "regions:\n  NYC:\n    delete: true\n", "NYC", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := mergeDefaults(t, map[string]string{"regions.yaml": tt.yaml})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := c.Regions[tt.region]
+			if !reflect.DeepEqual(got, tt.want) || ok != (tt.want != nil) {
+				t.Errorf("region %s = %q (present %v), want %q", tt.region, got, ok, tt.want)
+			}
+			if _, ok := c.Regions["TX"]; !ok {
+				t.Error("region TX was lost in the merge")
+			}
+			if len(c.Warnings) != 0 {
+				t.Errorf("Warnings = %q, want none", c.Warnings)
+			}
+		})
+	}
+}
+
+func TestOverrideErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		yaml string
+		want string
+	}{
+		{"unknown key", "regions.yaml", "regions:\n  NYC:\n    append: ['516']\n", "unknown key 'append'"},
+		{"unknown pattern key", "patterns.yaml", "patterns:\n  VIP:\n    drop: ['8675309']\n", "unknown key 'drop'"},
+		{"unknown tier", "patterns.yaml", "patterns:\n  Gold:\n    add: ['1234$']\n", "unknown tier 'Gold'"},
+		{"delete unknown tier", "patterns.yaml", "tiers:\n  - name: Gold\n    delete: true\n", "cannot delete unknown tier 'Gold'"},
+		{"not a list", "regions.yaml", "regions:\n  NYC: '212'\n", "expected a list or a mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mergeDefaults(t, map[string]string{tt.file: tt.yaml})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("merge error = %v, want %q", err, tt.want)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.file) {
+				t.Errorf("merge error = %v, want it to name %s", err, tt.file)
+			}
+		})
+	}
+}
+
+func TestOverrideUnmatchedRemove(t *testing.T) {
+	c, err := mergeDefaults(t, map[string]string{
+		// .*8675309.* is how the Jenny pattern was written before it lost its .*
+		"patterns.yaml": "patterns:\n  VIP:\n    remove: ['.*8675309.*', '(\\d{5})\\1']\n" +
+			"tiers:\n  - name: Notable\n    patterns:\n      remove: ['.*8449988.*']\n",
+		"regions.yaml": "regions:\n  NYC:\n    remove: ['212', '999']\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(c.Files[0]) + string(filepath.Separator)
+	var got []string
+	for _, w := range c.Warnings {
+		got = append(got, strings.TrimPrefix(w, dir))
+	}
+	want := []string{
+		"patterns.yaml: tier Notable: remove '.*8449988.*' matches nothing; see the current defaults with 'milk numbers config show'",
+		"patterns.yaml: tier VIP: remove '.*8675309.*' matches nothing; see the current defaults with 'milk numbers config show'",
+		"regions.yaml: region NYC: remove '999' matches nothing; see the current defaults with 'milk numbers config show'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings =\n%q\nwant\n%q", got, want)
+	}
+
+	// The entries that matched were still removed
+	if slices.Contains(regexes(c, "VIP"), `(\d{5})\1`) {
+		t.Error(`VIP still has (\d{5})\1`)
+	}
+	if slices.Contains(c.Regions["NYC"], "212") {
+		t.Error("NYC still has 212")
+	}
+}
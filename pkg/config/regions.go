@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/milktart/milk/pkg/query"
+)
+
+// Region expressions combine regions and area codes from left to right:
+//
+//	Canada+TX       union
+//	Canada-416-418  difference
+//	CA&NYC          intersection
+//
+// Each term is a region name (matched case-insensitively if there is no exact
+// match) or a query such as 212, 212-555 or ~8449988. An area code and exchange
+// such as 212-555 is read as one query, not as 212 minus 555, when it starts the
+// expression or follows '+' or '&'; after '-', as in NYC-212-646, each area code
+// is subtracted in turn. A region's own entries in regions.yaml may be
+// expressions too, so regions can be defined in terms of other regions.
+
+// regionOps are the operators allowed between terms of a region expression
+const regionOps = "+-&"
+
+// Eval evaluates a region expression, returning its area codes in order of first appearance
+func (r RegionsConfig) Eval(expr string) ([]string, error) {
+	return r.eval(expr, nil)
+}
+
+// eval evaluates expr, with stack holding the regions being expanded to detect cycles
+func (r RegionsConfig) eval(expr string, stack []string) ([]string, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty region expression")
+	}
+
+	var codes []string
+	op := byte('+')
+	for {
+		term, end := r.nextTerm(expr, op)
+		if term == "" {
+			return nil, fmt.Errorf("region expression is missing a term before '%s'", expr[end:])
+		}
+
+		termCodes, err := r.term(term, stack)
+		if err != nil {
+			return nil, err
+		}
+		codes = combine(codes, termCodes, op)
+
+		if end == len(expr) {
+			return codes, nil
+		}
+		op = expr[end]
+		expr = expr[end+1:]
+		if strings.TrimSpace(expr) == "" {
+			return nil, fmt.Errorf("region expression ends with '%c'", op)
+		}
+	}
+}
+
+// nextTerm returns the first term of expr, which follows op, and the index of
+// the operator after it, or len(expr) if it is the last term
+func (r RegionsConfig) nextTerm(expr string, op byte) (string, int) {
+	end := strings.IndexAny(expr, regionOps)
+	if end < 0 {
+		return strings.TrimSpace(expr), len(expr)
+	}
+	term := strings.TrimSpace(expr[:end])
+
+	// An exchange query contains the difference operator itself. Nothing
+	// subtracted is read as one, so NYC-212-646 removes both area codes.
+	if op != '-' && expr[end] == '-' && len(expr) >= end+4 {
+		rest := strings.TrimLeft(expr[end+4:], " \t")
+		_, region := r.lookup(term)
+		if !region && (rest == "" || strings.IndexByte(regionOps, rest[0]) >= 0) {
+			if q, err := query.Parse(expr[:end+4]); err == nil && q.Kind == query.Exchange {
+				return q.Text, len(expr) - len(rest)
+			}
+		}
+	}
+	return term, end
+}
+
+// term resolves a single region name or area code
+func (r RegionsConfig) term(term string, stack []string) ([]string, error) {
+	name, ok := r.lookup(term)
+	if !ok {
+		if _, err := query.Parse(term); err == nil {
+			return []string{term}, nil
+		}
+		return nil, fmt.Errorf("unknown region '%s' (available: %s)", term, strings.Join(r.Names(), ", "))
+	}
+
+	if slices.Contains(stack, name) {
+		cycle := append(stack[slices.Index(stack, name):], name)
+		return nil, fmt.Errorf("region cycle: %s", strings.Join(cycle, " -> "))
+	}
+	stack = append(stack, name)
+
+	var codes []string
+	for _, entry := range r[name] {
+		if _, err := query.Parse(entry); err == nil {
+			codes = combine(codes, []string{entry}, '+')
+			continue
+		}
+		entryCodes, err := r.eval(entry, stack)
+		if err != nil {
+			return nil, err
+		}
+		codes = combine(codes, entryCodes, '+')
+	}
+	return codes, nil
+}
+
+// lookup finds a region by name, preferring an exact match
+func (r RegionsConfig) lookup(name string) (string, bool) {
+	if _, ok := r[name]; ok {
+		return name, true
+	}
+	for key := range r {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Names returns the names of the regions, sorted
+func (r RegionsConfig) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validate evaluates every region so cycles and unknown references fail at load time
+func (r RegionsConfig) validate() error {
+	for _, name := range r.Names() {
+		if _, err := r.Eval(name); err != nil {
+			return fmt.Errorf("region '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// combine applies op to a and b, keeping the order of a followed by new codes from b
+func combine(a, b []string, op byte) []string {
+	var out []string
+	switch op {
+	case '+':
+		out = slices.Clone(a)
+		for _, code := range b {
+			if !slices.Contains(out, code) {
+				out = append(out, code)
+			}
+		}
+	case '-':
+		for _, code := range a {
+			if !slices.Contains(b, code) {
+				out = append(out, code)
+			}
+		}
+	case '&':
+		for _, code := range a {
+			if slices.Contains(b, code) {
+				out = append(out, code)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRegions is a small regions.yaml with regions defined in terms of others
+var testRegions = RegionsConfig{
+	"NYC":      {"212", "646", "718"},
+	"Canada":   {"416", "418", "514"},
+	"TX":       {"214", "512"},
+	"Borough":  {"718"},
+	"North":    {"Canada+NYC"},
+	"Big":      {"North-Borough", "TX"},
+	"Loop":     {"Around"},
+	"Around":   {"212", "Loop"},
+	"SelfLoop": {"SelfLoop+212"},
+}
+
+func TestRegionsEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"NYC", []string{"212", "646", "718"}},
+		{"nyc", []string{"212", "646", "718"}},
+		{"212", []string{"212"}},
+		{"Canada+TX", []string{"416", "418", "514", "214", "512"}},
+		{"NYC+212+917", []string{"212", "646", "718", "917"}},
+		{"Canada-416", []string{"418", "514"}},
+		{"NYC-Borough", []string{"212", "646"}},
+		{"NYC&Borough", []string{"718"}},
+		{"NYC&Canada", nil},
+		{"Canada+NYC&Borough", []string{"718"}},
+		{"Borough&NYC+Canada", []string{"718", "416", "418", "514"}},
+		{" NYC + TX ", []string{"212", "646", "718", "214", "512"}},
+		{"North", []string{"416", "418", "514", "212", "646", "718"}},
+		{"Big", []string{"416", "418", "514", "212", "646", "214", "512"}},
+		{"Big&TX", []string{"214", "512"}},
+	}
+
+	for _, tt := range tests {
+		got, err := testRegions.Eval(tt.expr)
+		if err != nil {
+			t.Errorf("Eval(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRegionsEvalExchange(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// An area code and exchange is one query at the start or after + and &
+		{"212-555", []string{"212-555"}},
+		{"NYC+212-555", []string{"212", "646", "718", "212-555"}},
+		{"212-555+TX", []string{"212-555", "214", "512"}},
+		{"NYC&212-555", nil},
+
+		// After -, each area code is subtracted
+		{"Canada+TX-416-418", []string{"514", "214", "512"}},
+		{"Canada-416-418", []string{"514"}},
+		{"NYC-212-646", []string{"718"}},
+		{"NYC - 212 - 646", []string{"718"}},
+		{"NYC-Borough-212", []string{"646"}},
+	}
+
+	for _, tt := range tests {
+		got, err := testRegions.Eval(tt.expr)
+		if err != nil {
+			t.Errorf("Eval(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRegionsEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty region expression"},
+		{"Atlantis", "unknown region 'Atlantis'"},
+		{"NYC+", "ends with '+'"},
+		{"+NYC", "missing a term"},
+		{"NYC&&TX", "missing a term"},
+		{"Loop", "region cycle: Loop -> Around -> Loop"},
+		{"NYC+Around", "region cycle: Around -> Loop -> Around"},
+		{"SelfLoop", "region cycle: SelfLoop -> SelfLoop"},
+	}
+
+	for _, tt := range tests {
+		_, err := testRegions.Eval(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestRegionsValidate(t *testing.T) {
+	if err := (RegionsConfig{"NYC": {"212"}, "North": {"NYC+416"}}).validate(); err != nil {
+		t.Errorf("validate of acyclic regions: %v", err)
+	}
+	err := (RegionsConfig{"NYC": {"212"}, "A": {"B"}, "B": {"NYC+A"}}).validate()
+	if err == nil || !strings.Contains(err.Error(), "region 'A': region cycle: A -> B -> A") {
+		t.Errorf("validate of cyclic regions = %v, want a cycle error", err)
+	}
+
+	// The shipped regions load cleanly
+	c, err := parseDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Regions.validate(); err != nil {
+		t.Errorf("default regions: %v", err)
+	}
+}
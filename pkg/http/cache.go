@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheMode controls when a Cache goes to the network
+type CacheMode int
+
+const (
+	CacheNormal  CacheMode = iota // use entries younger than the TTL, fetch the rest
+	CacheRefresh                  // always fetch, updating the cache
+	CacheOffline                  // never fetch; use entries of any age
+)
+
+// DefaultCacheTTL is how long search results are reused by default
+const DefaultCacheTTL = time.Hour
+
+// Cache stores the numbers each source returned for each query on disk, so
+// repeated searches can be re-classified without touching the network
+type Cache struct {
+	dir  string
+	ttl  time.Duration
+	mode CacheMode
+}
+
+// cacheEntry is the file stored for one source and query
+type cacheEntry struct {
+	Fetched time.Time `json:"fetched"`
+	Numbers []Number  `json:"numbers"`
+}
+
+// NewCache creates a Cache in dir
+func NewCache(dir string, ttl time.Duration, mode CacheMode) *Cache {
+	return &Cache{dir: dir, ttl: ttl, mode: mode}
+}
+
+// DefaultCacheDir returns $XDG_CACHE_HOME/milk, falling back to ~/.cache/milk
+func DefaultCacheDir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "milk-cache")
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "milk")
+}
+
+// Wrap returns src with its results read from and saved to the cache.
+// A nil Cache returns src unchanged.
+func (c *Cache) Wrap(src NumberSource) NumberSource {
+	if c == nil {
+		return src
+	}
+	return cachedSource{NumberSource: src, cache: c}
+}
+
+// path returns the file holding the results of source for query
+func (c *Cache) path(source, query string) string {
+	return filepath.Join(c.dir, "search", source, url.PathEscape(query)+".json")
+}
+
+// load reads the entry for source and query, if there is one
+func (c *Cache) load(source, query string) (cacheEntry, bool, error) {
+	var e cacheEntry
+	data, err := os.ReadFile(c.path(source, query))
+	if errors.Is(err, os.ErrNotExist) {
+		return e, false, nil
+	}
+	if err != nil {
+		return e, false, fmt.Errorf("failed to read cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		// A corrupt or outdated entry is treated as missing and overwritten by the next fetch
+		return e, false, nil
+	}
+	return e, true, nil
+}
+
+// save writes the entry for source and query atomically
+func (c *Cache) save(source, query string, e cacheEntry) error {
+	path := c.path(source, query)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// cachedSource is a NumberSource answered from a Cache when possible
+type cachedSource struct {
+	NumberSource
+	cache *Cache
+}
+
+// Search sends the cached numbers for query if allowed by the cache mode,
+// otherwise searches the wrapped source, caching what it sends once it succeeds
+func (s cachedSource) Search(client *http.Client, query string, out chan<- Number) error {
+	c := s.cache
+	if c.mode != CacheRefresh {
+		e, ok, err := c.load(s.Name(), query)
+		if err != nil {
+			return err
+		}
+		if ok && (c.mode == CacheOffline || time.Since(e.Fetched) < c.ttl) {
+			for _, n := range e.Numbers {
+				out <- n
+			}
+			return nil
+		}
+		if c.mode == CacheOffline {
+			return fmt.Errorf("'%s' is not cached and --offline was given", query)
+		}
+	}
+
+	// Record the numbers on their way through
+	var nums []Number
+	tee := make(chan Number)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(tee)
+		errc <- s.NumberSource.Search(client, query, tee)
+	}()
+	for n := range tee {
+		nums = append(nums, n)
+		out <- n
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	// Caching is best effort: an unwritable cache only costs a refetch next time
+	_ = c.save(s.Name(), query, cacheEntry{Fetched: time.Now(), Numbers: nums})
+	return nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milktart/milk/pkg/http/jmptest"
+)
+
+// cachedNumbers is an entry stored in the cache by tests
+var cachedNumbers = []Number{{E164: "+17189999999", Locality: "Brooklyn", Region: "NY"}}
+
+// newCacheTest returns a fake jmp.chat, a client for it and a cache in a
+// temporary directory holding cachedNumbers for 718, fetched age ago
+func newCacheTest(t *testing.T, mode CacheMode, age time.Duration) (*jmptest.Server, *http.Client, *Cache) {
+	t.Helper()
+	srv := jmptest.NewServer("testdata/jmp")
+	t.Cleanup(srv.Close)
+	c := NewCache(t.TempDir(), time.Hour, mode)
+	if err := c.save(JMPSource{}.Name(), "718", cacheEntry{Fetched: time.Now().Add(-age), Numbers: cachedNumbers}); err != nil {
+		t.Fatal(err)
+	}
+	return srv, &http.Client{Transport: srv.Transport()}, c
+}
+
+func TestCacheHit(t *testing.T) {
+	srv, client, c := newCacheTest(t, CacheNormal, time.Minute)
+
+	nums, err := search(t, client, c.Wrap(JMPSource{}), "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nums, cachedNumbers) {
+		t.Errorf("Search(718) = %+v, want the cached %+v", nums, cachedNumbers)
+	}
+	if got := srv.Requests(); len(got) != 0 {
+		t.Errorf("server received %q, want nothing", got)
+	}
+}
+
+func TestCacheExpired(t *testing.T) {
+	srv, client, c := newCacheTest(t, CacheNormal, 2*time.Hour)
+
+	nums, err := search(t, client, c.Wrap(JMPSource{}), "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"718"}) {
+		t.Fatalf("server received %q, want [718]", got)
+	}
+	if reflect.DeepEqual(nums, cachedNumbers) {
+		t.Error("Search(718) returned the expired entry")
+	}
+
+	// The fetch replaced the entry, so it is used next time
+	e, ok, err := c.load(JMPSource{}.Name(), "718")
+	if err != nil || !ok {
+		t.Fatalf("load(718) = %v, %v, want the fetched entry", ok, err)
+	}
+	if !reflect.DeepEqual(e.Numbers, nums) || time.Since(e.Fetched) > time.Minute {
+		t.Errorf("cached entry = %+v, want %+v fetched now", e, nums)
+	}
+	if _, err := search(t, client, c.Wrap(JMPSource{}), "718"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); len(got) != 1 {
+		t.Errorf("server received %q, want only the first request", got)
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	srv, client, c := newCacheTest(t, CacheRefresh, time.Minute)
+
+	nums, err := search(t, client, c.Wrap(JMPSource{}), "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"718"}) {
+		t.Fatalf("server received %q, want [718] despite the fresh entry", got)
+	}
+
+	e, ok, err := c.load(JMPSource{}.Name(), "718")
+	if err != nil || !ok {
+		t.Fatalf("load(718) = %v, %v, want the fetched entry", ok, err)
+	}
+	if !reflect.DeepEqual(e.Numbers, nums) {
+		t.Errorf("cached numbers = %+v, want the refetched %+v", e.Numbers, nums)
+	}
+}
+
+func TestCacheOffline(t *testing.T) {
+	srv, client, c := newCacheTest(t, CacheOffline, 48*time.Hour)
+
+	// Entries of any age are used
+	nums, err := search(t, client, c.Wrap(JMPSource{}), "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nums, cachedNumbers) {
+		t.Errorf("Search(718) = %+v, want the cached %+v", nums, cachedNumbers)
+	}
+
+	_, err = search(t, client, c.Wrap(JMPSource{}), "212")
+	if err == nil || !strings.Contains(err.Error(), "not cached") {
+		t.Errorf("Search(212) error = %v, want not cached", err)
+	}
+	if got := srv.Requests(); len(got) != 0 {
+		t.Errorf("server received %q, want nothing", got)
+	}
+}
+
+func TestCacheCorrupt(t *testing.T) {
+	srv, client, c := newCacheTest(t, CacheNormal, time.Minute)
+	path := c.path(JMPSource{}.Name(), "718")
+	if err := os.WriteFile(path, []byte(`{"fetched": "yesterday", "numbers": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := c.load(JMPSource{}.Name(), "718"); ok || err != nil {
+		t.Fatalf("load of a corrupt entry = %v, %v, want missing", ok, err)
+	}
+	nums, err := search(t, client, c.Wrap(JMPSource{}), "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"718"}) {
+		t.Errorf("server received %q, want [718]", got)
+	}
+	if e, ok, _ := c.load(JMPSource{}.Name(), "718"); !ok || !reflect.DeepEqual(e.Numbers, nums) {
+		t.Errorf("corrupt entry was not rewritten: %+v", e)
+	}
+}
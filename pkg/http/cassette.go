@@ -0,0 +1,192 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records or replays
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = iota // answer requests from the file, never touching the network
+	CassetteRecord                     // send requests on and save each exchange to the file
+)
+
+// cassetteHeaders are the response headers kept in a cassette
+var cassetteHeaders = []string{"Content-Type", "Retry-After"}
+
+// Cassette is an http.RoundTripper that records the responses to the requests
+// it sees to a file, or replays them from one, so searches can be repeated
+// offline with exactly the same results
+type Cassette struct {
+	path string
+	mode CassetteMode
+	next http.RoundTripper // used when recording; nil for http.DefaultTransport
+
+	mu           sync.Mutex
+	interactions []interaction
+	replayed     map[string]int // request key -> responses replayed so far
+}
+
+// interaction is one request and its response as stored in a cassette
+type interaction struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body"`
+}
+
+// key identifies the requests an interaction answers
+func (i interaction) key() string {
+	return i.Method + " " + i.URL
+}
+
+// LoadCassette opens the cassette at path for replay
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := &Cassette{path: path, mode: CassetteReplay, replayed: make(map[string]int)}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// NewRecorder creates a cassette at path recording the exchanges sent through
+// next, or http.DefaultTransport if next is nil. The file is rewritten after
+// every exchange.
+func NewRecorder(path string, next http.RoundTripper) *Cassette {
+	return &Cassette{path: path, mode: CassetteRecord, next: next}
+}
+
+// RoundTrip answers req from the cassette when replaying, or sends it on and
+// records the response when recording
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.mode == CassetteReplay {
+		return c.replay(req)
+	}
+	return c.record(req)
+}
+
+// replay returns the recorded responses to requests like req in the order they
+// were recorded, repeating the last one once they run out
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+
+	c.mu.Lock()
+	var matching []interaction
+	for _, i := range c.interactions {
+		if i.key() == key {
+			matching = append(matching, i)
+		}
+	}
+	if len(matching) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%s is not in cassette %s", key, c.path)
+	}
+	n := min(c.replayed[key], len(matching)-1)
+	c.replayed[key]++
+	c.mu.Unlock()
+
+	return matching[n].response(req), nil
+}
+
+// record sends req on and saves the exchange
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	next := c.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	i := interaction{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Body:   string(body),
+	}
+	for _, h := range cassetteHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if i.Header == nil {
+				i.Header = make(map[string]string)
+			}
+			i.Header[h] = v
+		}
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, i)
+	err = c.save()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// save writes the interactions to the cassette file, ordered by request so
+// recordings of concurrent searches are stable. The caller must hold c.mu.
+func (c *Cassette) save() error {
+	sorted := make([]interaction, len(c.interactions))
+	copy(sorted, c.interactions)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return sorted[a].key() < sorted[b].key()
+	})
+
+	// Keep recorded pages readable rather than escaping their markup
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sorted); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// response builds the recorded response to req
+func (i interaction) response(req *http.Request) *http.Response {
+	header := make(http.Header, len(i.Header))
+	for k, v := range i.Header {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+}
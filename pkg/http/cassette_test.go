@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milktart/milk/pkg/http/jmptest"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	srv := jmptest.NewServer("testdata/jmp")
+	srv.Fail("415", http.StatusTooManyRequests)
+	recorder := NewRecorder(path, srv.Transport())
+	client := &http.Client{Transport: recorder}
+
+	recorded, err := search(t, client, JMPSource{}, "212")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) == 0 {
+		t.Fatal("recorded no numbers")
+	}
+	_, recordedErr := search(t, client, JMPSource{}, "415")
+	srv.Close()
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: cassette}
+
+	replayed, err := search(t, client, JMPSource{}, "212")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed\n%+v\nwant\n%+v", replayed, recorded)
+	}
+
+	_, replayedErr := search(t, client, JMPSource{}, "415")
+	if StatusCode(replayedErr) != http.StatusTooManyRequests || StatusCode(recordedErr) != http.StatusTooManyRequests {
+		t.Errorf("replayed error %v, recorded %v, want status 429 for both", replayedErr, recordedErr)
+	}
+
+	_, err = search(t, client, JMPSource{}, "808")
+	if err == nil || !strings.Contains(err.Error(), "not in cassette") {
+		t.Errorf("Search(808) error = %v, want not in cassette", err)
+	}
+}
+
+func TestCassetteReplayOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// A failure then a success, as a retried request would record
+	srv := jmptest.NewServer("testdata/jmp")
+	recorder := NewRecorder(path, srv.Transport())
+	client := &http.Client{Transport: recorder}
+	srv.Fail("212", http.StatusBadGateway)
+	search(t, client, JMPSource{}, "212")
+	srv.Fail("212", 0)
+	search(t, client, JMPSource{}, "212")
+	srv.Close()
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: cassette}
+	for i, want := range []int{http.StatusBadGateway, 0, 0} {
+		_, err := search(t, client, JMPSource{}, "212")
+		if got := StatusCode(err); got != want {
+			t.Errorf("replay %d: status %d (%v), want %d", i, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// DefaultSource is the source used when none is selected
+const DefaultSource = "jmp"
+
+// JMPSource searches the jmp.chat number inventory
+type JMPSource struct{}
+
+func init() {
+	RegisterSource(JMPSource{})
+}
+
+// Name returns the source identifier
+func (JMPSource) Name() string { return "jmp" }
+
+// Host returns the jmp.chat host
+func (JMPSource) Host() string { return "jmp.chat" }
+
+// Search streams the numbers listed on the jmp.chat results page for query to out
+func (s JMPSource) Search(client *http.Client, query string, out chan<- Number) error {
+	resp, err := client.Get("https://" + s.Host() + "/tels?q=" + url.QueryEscape(query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := CheckStatus(resp); err != nil {
+		return err
+	}
+
+	return StreamNumbers(resp.Body, out)
+}
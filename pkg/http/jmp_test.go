@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/milktart/milk/pkg/http/jmptest"
+)
+
+// search collects what src sends for query
+func search(t *testing.T, client *http.Client, src NumberSource, query string) ([]Number, error) {
+	t.Helper()
+	out := make(chan Number)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(out)
+		errc <- src.Search(client, query, out)
+	}()
+	var nums []Number
+	for n := range out {
+		nums = append(nums, n)
+	}
+	return nums, <-errc
+}
+
+func TestJMPSearch(t *testing.T) {
+	srv := jmptest.NewServer("testdata/jmp")
+	defer srv.Close()
+	client := &http.Client{Transport: srv.Transport()}
+
+	nums, err := search(t, client, JMPSource{}, "718")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Number{
+		{E164: "+17185550000", Locality: "Brooklyn", Region: "NY", Price: "$5.00", Extra: map[string]string{"lata": "132"}},
+		{E164: "+17182222222", Locality: "Brooklyn", Region: "NY", Extra: map[string]string{"lata": "132"}},
+		{E164: "+17188888888", Locality: "Queens", Region: "NY", Extra: map[string]string{"lata": "132"}},
+		{E164: "+17182341234", Locality: "Staten Island", Region: "NY"},
+		{E164: "+17183908721", Locality: "Bronx", Region: "NY"},
+	}
+	if !reflect.DeepEqual(nums, want) {
+		t.Errorf("Search(718) =\n%+v\nwant\n%+v", nums, want)
+	}
+
+	if got := srv.Requests(); !reflect.DeepEqual(got, []string{"718"}) {
+		t.Errorf("server received %q, want [718]", got)
+	}
+}
+
+func TestJMPSearchNoResults(t *testing.T) {
+	srv := jmptest.NewServer("testdata/jmp")
+	defer srv.Close()
+	client := &http.Client{Transport: srv.Transport()}
+
+	nums, err := search(t, client, JMPSource{}, "907")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nums) != 0 {
+		t.Errorf("Search(907) = %v, want no numbers", nums)
+	}
+}
+
+func TestJMPSearchStatus(t *testing.T) {
+	srv := jmptest.NewServer("testdata/jmp")
+	defer srv.Close()
+	srv.Fail("212", http.StatusServiceUnavailable)
+	client := &http.Client{Transport: srv.Transport()}
+
+	_, err := search(t, client, JMPSource{}, "212")
+	if code := StatusCode(err); code != http.StatusServiceUnavailable {
+		t.Fatalf("Search(212) error = %v, want status 503", err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("IsTransient(%v) = false, want true", err)
+	}
+}
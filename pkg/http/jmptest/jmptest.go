@@ -0,0 +1,105 @@
+// Package jmptest provides a fake jmp.chat for tests.
+//
+// The server answers /tels?q=<query> with the page <query>.html from a
+// directory, where the query is path escaped (ex. ~8449988.html), and with an
+// empty results page for queries that have none.
+package jmptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// emptyPage is served for queries without a page
+const emptyPage = `<!DOCTYPE html>
+<html><head><title>Available numbers</title></head><body>
+<p>No numbers found</p>
+</body></html>
+`
+
+// Server is a fake jmp.chat serving result pages from a directory
+type Server struct {
+	*httptest.Server
+	dir string
+
+	mu       sync.Mutex
+	statuses map[string]int
+	requests []string
+}
+
+// NewServer starts a Server with the pages in dir. Close it when done.
+func NewServer(dir string) *Server {
+	s := &Server{dir: dir, statuses: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveTels))
+	return s
+}
+
+// Fail makes the server answer query with status instead of its page
+func (s *Server) Fail(query string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[query] = status
+}
+
+// Requests returns the queries received so far, in order
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Transport returns an http.RoundTripper sending requests for any host to the
+// server, so code that fetches https://jmp.chat can be pointed at it
+func (s *Server) Transport() http.RoundTripper {
+	target, _ := url.Parse(s.URL)
+	return rewriteTransport{target: target, next: s.Client().Transport}
+}
+
+// serveTels answers a number search
+func (s *Server) serveTels(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/tels" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query().Get("q")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, query)
+	status := s.statuses[query]
+	s.mu.Unlock()
+
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	page, err := os.ReadFile(filepath.Join(s.dir, url.PathEscape(query)+".html"))
+	switch {
+	case os.IsNotExist(err):
+		page = []byte(emptyPage)
+	case err != nil:
+		http.Error(w, fmt.Sprintf("failed to read page: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// rewriteTransport sends every request to target instead of its own host
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	r.Host = ""
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Number is a phone number offered by a provider, with whatever the provider
+// says about it
+type Number struct {
+	E164       string            `json:"e164"`
+	Locality   string            `json:"locality,omitempty"`    // city or town, ex. Brooklyn
+	Region     string            `json:"region,omitempty"`      // state or province, ex. NY
+	RateCenter string            `json:"rate_center,omitempty"` // telco rate center, ex. NWYRCYZN01
+	Price      string            `json:"price,omitempty"`       // as shown by the provider, ex. $5.00
+	Extra      map[string]string `json:"extra,omitempty"`       // any other data-* attributes
+}
+
+// Location returns the locality and region joined for display, ex. Brooklyn, NY
+func (n Number) Location() string {
+	switch {
+	case n.Locality != "" && n.Region != "":
+		return n.Locality + ", " + n.Region
+	case n.Locality != "":
+		return n.Locality
+	}
+	return n.Region
+}
+
+// Details returns the Extra attributes as sorted key=value pairs, ex. lata=132
+func (n Number) Details() []string {
+	details := make([]string, 0, len(n.Extra))
+	for key, val := range n.Extra {
+		details = append(details, key+"="+val)
+	}
+	slices.Sort(details)
+	return details
+}
+
+// setAttr records a data-* attribute of the number's anchor or row
+func (n *Number) setAttr(key, val string) {
+	key, ok := strings.CutPrefix(key, "data-")
+	if !ok || val == "" {
+		return
+	}
+	switch key {
+	case "locality", "city":
+		n.Locality = val
+	case "region", "state", "province":
+		n.Region = val
+	case "rate-center", "ratecenter":
+		n.RateCenter = val
+	case "price":
+		n.Price = val
+	default:
+		if n.Extra == nil {
+			n.Extra = make(map[string]string)
+		}
+		n.Extra[key] = val
+	}
+}
+
+var (
+	// localityRE matches a cell such as "Brooklyn, NY"
+	localityRE = regexp.MustCompile(`^([^,$]+),\s*([A-Z]{2})$`)
+	// priceRE matches a cell such as "$5.00"
+	priceRE = regexp.MustCompile(`^[$€£]\s?\d+(?:[.,]\d{2})?$`)
+)
+
+// setText reads a text cell from the number's row: a locality or a price
+func (n *Number) setText(text string) {
+	if m := localityRE.FindStringSubmatch(text); m != nil && n.Locality == "" {
+		n.Locality, n.Region = m[1], m[2]
+	} else if priceRE.MatchString(text) && n.Price == "" {
+		n.Price = text
+	}
+}
+
+// StreamNumbers tokenizes the HTML in r and sends each number linked from an
+// anchor whose href ends with one (ex. tel:+12125551234) to out. Numbers in a
+// table row or list item are sent when the row ends, with the metadata found
+// in the row's text cells and data-* attributes, where the anchor's own
+// attributes take precedence over the row's; others are sent as soon as their
+// anchor is read. It does not close out.
+func StreamNumbers(r io.Reader, out chan<- Number) error {
+	z := html.NewTokenizer(r)
+
+	var (
+		row      bool              // inside a tr or li
+		rowAttrs map[string]string // data-* attributes of the row
+		pending  *Number           // number found in the row, sent when it ends
+		texts    []string          // text of the row outside the anchor, before or after it
+		inAnchor bool
+	)
+	flush := func() {
+		if pending != nil {
+			for _, t := range texts {
+				pending.setText(t)
+			}
+			out <- *pending
+		}
+		pending, texts = nil, nil
+	}
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			flush()
+			return nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "tr", "li":
+				flush()
+				row = true
+				rowAttrs = nil
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if bytes.HasPrefix(key, []byte("data-")) {
+						if rowAttrs == nil {
+							rowAttrs = make(map[string]string)
+						}
+						rowAttrs[string(key)] = string(val)
+					}
+				}
+
+			case "a":
+				n, ok := anchorNumber(z, hasAttr, rowAttrs)
+				if !ok {
+					continue
+				}
+				if !row {
+					out <- n
+					continue
+				}
+				// A second number in the row takes the text that follows it
+				if pending != nil {
+					flush()
+				}
+				pending = &n
+				inAnchor = true
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "tr", "li":
+				flush()
+				row = false
+				rowAttrs = nil
+			case "a":
+				inAnchor = false
+			}
+
+		case html.TextToken:
+			if row && !inAnchor {
+				if text := bytes.TrimSpace(z.Text()); len(text) > 0 {
+					texts = append(texts, string(text))
+				}
+			}
+		}
+	}
+}
+
+// anchorNumber reads the number and data-* attributes of the anchor tag z is on,
+// applied over the data-* attributes of its row
+func anchorNumber(z *html.Tokenizer, hasAttr bool, rowAttrs map[string]string) (Number, bool) {
+	var n Number
+	for key, val := range rowAttrs {
+		n.setAttr(key, val)
+	}
+	found := false
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		switch {
+		case string(key) == "href":
+			n.E164, found = hrefNumber(string(val))
+		case bytes.HasPrefix(key, []byte("data-")):
+			n.setAttr(string(key), string(val))
+		}
+	}
+	return n, found
+}
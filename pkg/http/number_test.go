@@ -0,0 +1,105 @@
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []Number
+	}{
+		{
+			name: "table row",
+			page: `<table><tr data-rate-center="NWYRCYZN01"><td><a href="tel:+12125550000">(212) 555-0000</a></td>` +
+				`<td>New York, NY</td><td>$5.00</td></tr></table>`,
+			want: []Number{{E164: "+12125550000", Locality: "New York", Region: "NY", RateCenter: "NWYRCYZN01", Price: "$5.00"}},
+		},
+		{
+			name: "list item attributes",
+			page: `<ul><li data-city="Brooklyn" data-state="NY" data-lata="132"><a href="/register?number=7185550000">x</a></li></ul>`,
+			want: []Number{{E164: "+17185550000", Locality: "Brooklyn", Region: "NY", Extra: map[string]string{"lata": "132"}}},
+		},
+		{
+			name: "anchor attributes",
+			page: `<a data-locality="Queens" data-price="$1.00" href="tel:+17188888888">x</a>`,
+			want: []Number{{E164: "+17188888888", Locality: "Queens", Price: "$1.00"}},
+		},
+		{
+			name: "anchor attributes override the row",
+			page: `<table><tr data-city="New York" data-price="$5.00" data-lata="132">` +
+				`<td><a data-city="Manhattan" data-lata="133" href="tel:+12125550000">x</a></td></tr></table>`,
+			want: []Number{{E164: "+12125550000", Locality: "Manhattan", Price: "$5.00", Extra: map[string]string{"lata": "133"}}},
+		},
+		{
+			name: "cells before the anchor",
+			page: `<table><tr><td>$2.00</td><td><a href="/city/albany">Albany, NY</a></td>` +
+				`<td><a href="tel:+15185550000">x</a></td></tr></table>`,
+			want: []Number{{E164: "+15185550000", Locality: "Albany", Region: "NY", Price: "$2.00"}},
+		},
+		{
+			name: "anchor text is not metadata",
+			page: `<table><tr><td><a href="tel:+12125550000">Albany, NY</a></td></tr></table>`,
+			want: []Number{{E164: "+12125550000"}},
+		},
+		{
+			name: "two numbers in a row",
+			page: `<table><tr><td><a href="tel:+12125550000">a</a> Albany, NY</td><td><a href="tel:+12125551111">b</a> Troy, NY</td></tr></table>`,
+			want: []Number{
+				{E164: "+12125550000", Locality: "Albany", Region: "NY"},
+				{E164: "+12125551111", Locality: "Troy", Region: "NY"},
+			},
+		},
+		{
+			name: "escaped international",
+			page: `<a href="tel:%2B442079460958">x</a>`,
+			want: []Number{{E164: "+442079460958"}},
+		},
+		{
+			name: "not numbers",
+			page: `<table><tr><td><a href="/faq">FAQ</a></td><td>Albany, NY</td></tr></table><a href="tel:555">x</a>`,
+		},
+		{
+			name: "unclosed row",
+			page: `<table><tr><td><a href="tel:+12125550000">x</a><td>Albany, NY`,
+			want: []Number{{E164: "+12125550000", Locality: "Albany", Region: "NY"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := make(chan Number, 10)
+			if err := StreamNumbers(strings.NewReader(tt.page), out); err != nil {
+				t.Fatal(err)
+			}
+			close(out)
+			var got []Number
+			for n := range out {
+				got = append(got, n)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberLocation(t *testing.T) {
+	tests := []struct {
+		n    Number
+		want string
+	}{
+		{Number{Locality: "Brooklyn", Region: "NY"}, "Brooklyn, NY"},
+		{Number{Locality: "London"}, "London"},
+		{Number{Region: "ON"}, "ON"},
+		{Number{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.n.Location(); got != tt.want {
+			t.Errorf("%+v.Location() = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HostLimiter spaces out requests to the same host by a minimum interval
+type HostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// NewHostLimiter creates a HostLimiter allowing one request per interval per host
+func NewHostLimiter(interval time.Duration) *HostLimiter {
+	return &HostLimiter{
+		interval: interval,
+		next:     make(map[string]time.Time),
+	}
+}
+
+// Wait blocks until a request to host is allowed
+func (l *HostLimiter) Wait(host string) {
+	if l == nil || l.interval <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// Wrap returns src with every search first waiting for its host's turn.
+// A nil HostLimiter returns src unchanged.
+func (l *HostLimiter) Wrap(src NumberSource) NumberSource {
+	if l == nil {
+		return src
+	}
+	return limitedSource{NumberSource: src, limiter: l}
+}
+
+// limitedSource is a NumberSource whose searches are spaced out by a HostLimiter
+type limitedSource struct {
+	NumberSource
+	limiter *HostLimiter
+}
+
+// Search waits for the host's turn and then searches the wrapped source
+func (s limitedSource) Search(client *http.Client, query string, out chan<- Number) error {
+	s.limiter.Wait(s.Host())
+	return s.NumberSource.Search(client, query, out)
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusError is returned when a provider answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// CheckStatus returns a StatusError if resp does not have a 2xx status
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// StatusCode returns the HTTP status behind err, or 0 if there is none
+func StatusCode(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode
+	}
+	return 0
+}
+
+// RetryPolicy controls how transient failures are retried
+type RetryPolicy struct {
+	Retries int           // attempts after the first; 0 disables retrying
+	Base    time.Duration // delay before the first retry, doubled each time
+	Max     time.Duration // longest delay, including one asked for by Retry-After
+}
+
+// DefaultRetryPolicy retries three times, waiting about 0.5s, 1s and 2s
+var DefaultRetryPolicy = RetryPolicy{Retries: 3, Base: 500 * time.Millisecond, Max: 30 * time.Second}
+
+// Do calls fn until it succeeds, fails with an error that is not transient,
+// or runs out of retries, and returns the last error
+func (p RetryPolicy) Do(fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Retries || !IsTransient(err) {
+			return err
+		}
+
+		delay, ok := p.delay(attempt, err)
+		if !ok {
+			return err
+		}
+		time.Sleep(delay)
+	}
+}
+
+// delay returns how long to wait before retry number attempt+1: the server's
+// Retry-After if it gave one, otherwise exponential backoff with full jitter.
+// It reports false if the server asked for a longer wait than p.Max.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		return se.RetryAfter, se.RetryAfter <= p.Max
+	}
+
+	backoff := p.Base << attempt
+	if backoff <= 0 || backoff > p.Max {
+		backoff = p.Max
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	return rand.N(backoff) + 1, true
+}
+
+// IsTransient reports whether err is worth retrying: timeouts, 5xx and 429
+func IsTransient(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -2,65 +2,237 @@ package http
 
 import (
 	"net/http"
+	"net/url"
 	"sort"
+	"strings"
 
-	"github.com/dlclark/regexp2"
-	"golang.org/x/net/html"
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/phone"
+	"github.com/milktart/milk/pkg/query"
+	"github.com/milktart/milk/pkg/vanity"
 )
 
-// ExtractNumbers extracts phone numbers from an HTTP response
-// Returns: (notable numbers, platinum numbers, VIP numbers, error)
+// PatternMatch records a pattern that matched a number and where.
+// For vanity matches Regex is empty and Label holds the word spelled.
+type PatternMatch struct {
+	Tier  string // name of the tier the pattern belongs to
+	Regex string
+	Label string
+	// Groups holds the [start, end) span of the whole match followed by each
+	// capture group; groups that did not participate are {-1, -1}
+	Groups [][2]int
+}
+
+// Name returns the pattern's label, or its regex if it has none
+func (m PatternMatch) Name() string {
+	if m.Label != "" {
+		return m.Label
+	}
+	return m.Regex
+}
+
+// IsWord reports whether this is a vanity word match rather than a regex match
+func (m PatternMatch) IsWord() bool {
+	return m.Regex == ""
+}
+
+// Highlight returns the span of digits that made the pattern match: from the
+// first capture group to the end of the match, or the whole match if the
+// pattern has no groups. This skips leading filler such as ".*" or "\d{3}".
+func (m PatternMatch) Highlight() (int, int) {
+	start, end := m.Groups[0][0], m.Groups[0][1]
+	for _, g := range m.Groups[1:] {
+		if g[0] >= 0 {
+			start = g[0]
+			break
+		}
+	}
+	return start, end
+}
+
+// Classification is the result of matching E.164 numbers against the tier patterns.
+// PatternMatch spans index into each number's national significant number.
+type Classification struct {
+	Tiers   map[string][]string       // tier name -> numbers in the tier
+	Matches map[string][]PatternMatch // every pattern each number matched
+	Info    map[string]Number         // what the provider said about each number
+
+	// Timeouts holds the patterns that ran out of time on each number and so
+	// were treated as not matching; their Groups are empty
+	Timeouts map[string][]PatternMatch
+}
+
+// ExtractNumbers searches a source for q and classifies the returned numbers that
+// satisfy it as they arrive. Nothing is returned if the search fails part way.
 func ExtractNumbers(
-	resp *http.Response,
-	compiledNotable []*regexp2.Regexp,
-	compiledPlatinum []*regexp2.Regexp,
-	compiledVIP []*regexp2.Regexp,
-) ([]string, []string, []string, error) {
-	defer resp.Body.Close()
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return nil, nil, nil, err
+	client *http.Client,
+	src NumberSource,
+	q query.Query,
+	tiers []config.CompiledTier,
+	words *vanity.Dictionary,
+) (Classification, error) {
+	found := make(chan Number, 64)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(found)
+		errc <- src.Search(client, q.Param(), found)
+	}()
+
+	c := newClassification(len(tiers))
+	for n := range found {
+		if q.Match(n.E164) {
+			c.add(n.E164, tiers, words)
+			c.Info[n.E164] = n
+		}
+	}
+	if err := <-errc; err != nil {
+		return Classification{}, err
 	}
+	return c, nil
+}
 
-	var numbers, platinum, VIP []string
-	for _, num := range getHrefNumbers(doc) {
-		if matchesAny(num, compiledNotable) {
-			numbers = append(numbers, num)
+// ClassifyNumbers sorts candidate E.164 numbers into the tiers whose patterns they
+// match. Numbers whose last digits spell a word also join any vanity tier.
+func ClassifyNumbers(candidates []string, tiers []config.CompiledTier, words *vanity.Dictionary) Classification {
+	c := newClassification(len(tiers))
+	for _, num := range candidates {
+		c.add(num, tiers, words)
+	}
+	return c
+}
+
+// newClassification creates an empty Classification
+func newClassification(tiers int) Classification {
+	return Classification{
+		Tiers:    make(map[string][]string, tiers),
+		Matches:  make(map[string][]PatternMatch),
+		Info:     make(map[string]Number),
+		Timeouts: make(map[string][]PatternMatch),
+	}
+}
+
+// add classifies a single E.164 number, ignoring strings that are not one
+func (c Classification) add(num string, tiers []config.CompiledTier, words *vanity.Dictionary) {
+	n, ok := phone.Split(num)
+	if !ok {
+		return
+	}
+	for _, t := range tiers {
+		m, timedOut := matchAll(n, t.Name, t.Patterns)
+		if len(timedOut) > 0 {
+			c.Timeouts[num] = append(c.Timeouts[num], timedOut...)
 		}
-		if matchesAny(num, compiledPlatinum) {
-			platinum = append(platinum, num)
+		if t.Vanity {
+			if word, start, ok := words.Match(n.National); ok {
+				m = append(m, PatternMatch{
+					Tier:   t.Name,
+					Label:  word,
+					Groups: [][2]int{{start, len(n.National)}},
+				})
+			}
 		}
-		if matchesAny(num, compiledVIP) {
-			VIP = append(VIP, num)
+		if len(m) > 0 {
+			c.Tiers[t.Name] = append(c.Tiers[t.Name], num)
+			c.Matches[num] = append(c.Matches[num], m...)
 		}
 	}
-	return numbers, platinum, VIP, nil
 }
 
-// getHrefNumbers recursively extracts phone numbers from href attributes
-func getHrefNumbers(n *html.Node) []string {
-	var nums []string
-	if n.Type == html.ElementNode && n.Data == "a" {
-		for _, attr := range n.Attr {
-			if attr.Key == "href" && len(attr.Val) >= 10 {
-				nums = append(nums, attr.Val[len(attr.Val)-10:])
+// hrefNumberParams are the query parameters a link may carry a number in, ex. /register?number=2125551234
+var hrefNumberParams = []string{"number", "phone", "tel"}
+
+// hrefNumber returns the E.164 number a link points to: the number of an
+// RFC 3966 tel: link, ex. tel:+1-212-555-1234, or of a hrefNumberParams query
+// parameter. Other links, such as /order/1234567890, hold no number. Numbers
+// without a + are read as NANP numbers.
+func hrefNumber(href string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+
+	var num string
+	if strings.EqualFold(u.Scheme, "tel") {
+		// Parameters such as ;ext=123 follow the number
+		num, _, _ = strings.Cut(u.Opaque, ";")
+		if num, err = url.PathUnescape(num); err != nil {
+			return "", false
+		}
+	} else {
+		params := u.Query()
+		for _, p := range hrefNumberParams {
+			if num = params.Get(p); num != "" {
+				break
 			}
 		}
 	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		nums = append(nums, getHrefNumbers(c)...)
+
+	digits, ok := stripSeparators(num)
+	if !ok {
+		return "", false
+	}
+	return phone.Normalize(digits)
+}
+
+// stripSeparators removes the visual separators allowed in a tel: number
+// (-, ., parentheses and spaces), failing if anything but digits and a leading + remain
+func stripSeparators(s string) (string, bool) {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("-.() ", r) {
+			return -1
+		}
+		return r
+	}, s)
+	digits := strings.TrimPrefix(s, "+")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", false
+	}
+	return s, true
+}
+
+// matchAll returns every pattern in a tier that applies to n's country and
+// matches its national number, with group spans, and those that timed out
+func matchAll(n phone.Number, tier string, patterns []config.CompiledPattern) (matches, timedOut []PatternMatch) {
+	for _, p := range patterns {
+		if !p.AppliesTo(n.Country) {
+			continue
+		}
+		m, ok, err := MatchPattern(n.National, tier, p)
+		switch {
+		case err != nil:
+			timedOut = append(timedOut, PatternMatch{Tier: tier, Regex: p.Regex, Label: p.Label})
+		case ok:
+			matches = append(matches, m)
+		}
 	}
-	return nums
+	return matches, timedOut
 }
 
-// matchesAny checks if a number matches any regex in a slice
-func matchesAny(number string, regexes []*regexp2.Regexp) bool {
-	for _, re := range regexes {
-		if match, _ := re.MatchString(number); match {
-			return true
+// MatchPattern matches a single pattern against a national number, recording its
+// group spans. The error is set if the match ran past config.MatchTimeout.
+func MatchPattern(number, tier string, p config.CompiledPattern) (PatternMatch, bool, error) {
+	m, err := p.Re.FindStringMatch(number)
+	if err != nil {
+		return PatternMatch{}, false, err
+	}
+	if m == nil {
+		return PatternMatch{}, false, nil
+	}
+	groups := m.Groups()
+	spans := make([][2]int, len(groups))
+	for i, g := range groups {
+		if len(g.Captures) == 0 {
+			spans[i] = [2]int{-1, -1}
+		} else {
+			spans[i] = [2]int{g.Index, g.Index + g.Length}
 		}
 	}
-	return false
+	return PatternMatch{
+		Tier:   tier,
+		Regex:  p.Regex,
+		Label:  p.Label,
+		Groups: spans,
+	}, true, nil
 }
 
 // DeduplicateAndSort removes duplicates and sorts a slice of strings
@@ -0,0 +1,267 @@
+package http
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/http/jmptest"
+	"github.com/milktart/milk/pkg/query"
+	"golang.org/x/net/html"
+)
+
+// largePage is a synthetic results page listing 3000 numbers, generated in the
+// markup of a jmp.chat results page since no large live page has been recorded.
+// -page-cassette benchmarks a recorded page instead.
+const largePage = "testdata/tels_large.html"
+
+// largePageNumbers is how many numbers largePage lists
+const largePageNumbers = 3000
+
+var pageCassette = flag.String("page-cassette", "",
+	"Benchmark the largest page in this cassette, recorded with milk numbers --record, instead of "+largePage)
+
+// readPage returns the page to benchmark and how many numbers it lists
+func readPage(b *testing.B) ([]byte, int) {
+	b.Helper()
+	if *pageCassette == "" {
+		page, err := os.ReadFile(largePage)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return page, largePageNumbers
+	}
+
+	c, err := LoadCassette(*pageCassette)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var page []byte
+	for _, i := range c.interactions {
+		if len(i.Body) > len(page) {
+			page = []byte(i.Body)
+		}
+	}
+	out := make(chan Number, 64)
+	go func() {
+		defer close(out)
+		StreamNumbers(bytes.NewReader(page), out)
+	}()
+	n := 0
+	for range out {
+		n++
+	}
+	if n == 0 {
+		b.Fatalf("cassette %s has no page listing numbers", *pageCassette)
+	}
+	return page, n
+}
+
+// parseHrefNumbers is the previous extractor, which builds the whole DOM and
+// walks it recursively; it is kept as the baseline for the benchmarks
+func parseHrefNumbers(n *html.Node) []string {
+	var nums []string
+	if n.Type == html.ElementNode && n.Data == "a" {
+		for _, attr := range n.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			if num, ok := hrefNumber(attr.Val); ok {
+				nums = append(nums, num)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nums = append(nums, parseHrefNumbers(c)...)
+	}
+	return nums
+}
+
+func BenchmarkParseHrefNumbers(b *testing.B) {
+	page, want := readPage(b)
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for b.Loop() {
+		doc, err := html.Parse(bytes.NewReader(page))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n := len(parseHrefNumbers(doc)); n != want {
+			b.Fatalf("found %d numbers, want %d", n, want)
+		}
+	}
+}
+
+func BenchmarkStreamNumbers(b *testing.B) {
+	page, want := readPage(b)
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for b.Loop() {
+		out := make(chan Number, 64)
+		errc := make(chan error, 1)
+		go func() {
+			defer close(out)
+			errc <- StreamNumbers(bytes.NewReader(page), out)
+		}()
+		n := 0
+		for range out {
+			n++
+		}
+		if err := <-errc; err != nil {
+			b.Fatal(err)
+		}
+		if n != want {
+			b.Fatalf("found %d numbers, want %d", n, want)
+		}
+	}
+}
+
+// pageSource serves a recorded page instead of fetching one
+type pageSource struct {
+	page []byte
+}
+
+func (pageSource) Name() string { return "page" }
+func (pageSource) Host() string { return "" }
+
+func (s pageSource) Search(_ *http.Client, _ string, out chan<- Number) error {
+	return StreamNumbers(bytes.NewReader(s.page), out)
+}
+
+func BenchmarkExtractNumbers(b *testing.B) {
+	cfg, err := config.LoadFromBytes()
+	if err != nil {
+		b.Fatal(err)
+	}
+	q, err := query.Parse("212")
+	if err != nil {
+		b.Fatal(err)
+	}
+	page, _ := readPage(b)
+	src := pageSource{page: page}
+	b.SetBytes(int64(len(src.page)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ExtractNumbers(nil, src, q, cfg.CompiledTiers, cfg.CompiledWords); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestClassifyNumbersTimeout(t *testing.T) {
+	// Nested quantifiers try every way of splitting the digits between the groups
+	slow, err := config.CompileRegex(`(\d*)*(\d*)*(\d*)*(\d*)*(\d*)*a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := config.CompileRegex(`0000$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiers := []config.CompiledTier{{
+		Name: "Slow",
+		Patterns: []config.CompiledPattern{
+			{Pattern: config.Pattern{Regex: slow.String()}, Re: slow},
+			{Pattern: config.Pattern{Regex: fast.String()}, Re: fast},
+		},
+	}}
+
+	const num = "+442079460000"
+	c := ClassifyNumbers([]string{num}, tiers, nil)
+	if got := c.Timeouts[num]; len(got) != 1 || got[0].Regex != slow.String() || got[0].Tier != "Slow" {
+		t.Errorf("Timeouts[%s] = %+v, want the slow pattern", num, got)
+	}
+	if got := c.Tiers["Slow"]; len(got) != 1 || got[0] != num {
+		t.Errorf("Tiers[Slow] = %v, want %s from the fast pattern", got, num)
+	}
+}
+
+func TestExtractNumbersQuery(t *testing.T) {
+	srv := jmptest.NewServer("testdata/jmp")
+	defer srv.Close()
+	client := &http.Client{Transport: srv.Transport()}
+
+	tests := []struct {
+		query string
+		sent  string   // what the provider is asked for
+		want  []string // the numbers kept, sorted
+	}{
+		{"718", "718", []string{"+17182222222", "+17182341234", "+17183908721", "+17185550000", "+17188888888"}},
+		{"718-555", "718", []string{"+17185550000"}},
+		{"718-222", "718", []string{"+17182222222"}},
+		{"5555", "~5555", []string{"+13125555555", "+14155550199", "+18085555123", "+442079465555"}},
+	}
+
+	for _, tt := range tests {
+		q, err := query.Parse(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := len(srv.Requests())
+		c, err := ExtractNumbers(client, JMPSource{}, q, nil, nil)
+		if err != nil {
+			t.Fatalf("ExtractNumbers(%s): %v", tt.query, err)
+		}
+
+		if got := srv.Requests()[before:]; !reflect.DeepEqual(got, []string{tt.sent}) {
+			t.Errorf("ExtractNumbers(%s) sent %q, want [%s]", tt.query, got, tt.sent)
+		}
+		var got []string
+		for num := range c.Info {
+			got = append(got, num)
+		}
+		slices.Sort(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractNumbers(%s) kept %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHrefNumber(t *testing.T) {
+	tests := []struct {
+		href string
+		want string // empty if the link holds no number
+	}{
+		{"tel:+12125551234", "+12125551234"},
+		{"tel:2125551234", "+12125551234"},
+		{"tel:+1-212-555-1234", "+12125551234"},
+		{"tel:+1.212.555.1234", "+12125551234"},
+		{"tel:+1(212)555-1234", "+12125551234"},
+		{"tel:+1%20212%20555%201234", "+12125551234"},
+		{"TEL:+442079460958", "+442079460958"},
+		{"tel:+44-20-7946-0958;ext=12", "+442079460958"},
+		{"/register?number=7185550000", "+17185550000"},
+		{"/register?number=%2B17185550000&plan=1", "+17185550000"},
+		{"https://example.com/buy?phone=718-555-0000", "+17185550000"},
+
+		// Paths and other parameters are not numbers
+		{"/order/1234567890", ""},
+		{"/order/2125551234", ""},
+		{"/register/7185550000", ""},
+		{"/search?page=2125551234", ""},
+		{"mailto:2125551234@example.com", ""},
+
+		// Area codes and exchanges may not start with 0 or 1
+		{"tel:+10125551234", ""},
+		{"tel:+11125551234", ""},
+		{"tel:+12120551234", ""},
+		{"tel:+12121231234", ""},
+		{"/register?number=7181231234", ""},
+
+		{"tel:+1-212-555-12x4", ""},
+		{"tel:+1-212-555", ""},
+		{"tel:", ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := hrefNumber(tt.href)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("hrefNumber(%q) = %q, %v, want %q", tt.href, got, ok, tt.want)
+		}
+	}
+}
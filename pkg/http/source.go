@@ -0,0 +1,50 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// NumberSource is a provider of available phone numbers
+type NumberSource interface {
+	// Name is the identifier used to select the source (ex. --source jmp)
+	Name() string
+	// Host is the host the source talks to, used for rate limiting
+	Host() string
+	// Search sends the numbers the provider offers for a query to out as
+	// they are found, without closing it
+	Search(client *http.Client, query string, out chan<- Number) error
+}
+
+var sources = map[string]NumberSource{}
+
+// RegisterSource makes a NumberSource available by name
+func RegisterSource(src NumberSource) {
+	name := strings.ToLower(src.Name())
+	if _, dup := sources[name]; dup {
+		panic(fmt.Sprintf("number source '%s' registered twice", name))
+	}
+	sources[name] = src
+}
+
+// LookupSource returns the registered source with the given name
+func LookupSource(name string) (NumberSource, error) {
+	src, ok := sources[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown number source '%s' (available: %s)",
+			name, strings.Join(SourceNames(), ", "))
+	}
+	return src, nil
+}
+
+// SourceNames returns the names of all registered sources, sorted
+func SourceNames() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+// command runs a local program once per match, with its output sent to stderr.
+// The match is passed in MILK_NUMBER, MILK_E164, MILK_TIERS and MILK_SOURCES.
+type command struct {
+	argv []string
+}
+
+func newCommand(c config.NotifierConfig) (*command, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	return &command{argv: c.Command}, nil
+}
+
+// Notify runs the command for each match, stopping at the first failure
+func (c *command) Notify(matches []Match) error {
+	for _, m := range matches {
+		cmd := exec.Command(c.argv[0], c.argv[1:]...)
+		// Keep stdout free for machine-readable results
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(),
+			"MILK_NUMBER="+m.Number,
+			"MILK_E164="+m.E164,
+			"MILK_TIERS="+strings.Join(m.Tiers, ","),
+			"MILK_SOURCES="+strings.Join(m.Sources, ","),
+		)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", c.argv[0], err)
+		}
+	}
+	return nil
+}
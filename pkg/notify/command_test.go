@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	out := filepath.Join(t.TempDir(), "env.txt")
+	d, err := New([]config.NotifierConfig{{
+		Type:    "command",
+		Command: []string{"sh", "-c", `printf '%s %s %s %s\n' "$MILK_NUMBER" "$MILK_E164" "$MILK_TIERS" "$MILK_SOURCES" >> "$0"`, out},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(testMatches); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2125551234 +12125551234 VIP jmp\n" +
+		"7188888888 +17188888888 Platinum,Notable \n"
+	if string(data) != want {
+		t.Errorf("command saw\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestCommandFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	d, err := New([]config.NotifierConfig{{Type: "command", Command: []string{"sh", "-c", "exit 2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Notify(testMatches)
+	if err == nil || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("Notify error = %v, want exit status 2", err)
+	}
+
+	if _, err := New([]config.NotifierConfig{{Type: "command"}}); err == nil {
+		t.Error("New without a command succeeded, want an error")
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	d, err := New([]config.NotifierConfig{{Type: "command", Command: []string{"sh", "-c", `echo "notified $MILK_E164"`}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedOut, savedErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	err = d.Notify(testMatches[:1])
+	os.Stdout, os.Stderr = savedOut, savedErr
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stdout may carry --output json, csv or ndjson, so the command must not write to it
+	if out, _ := os.ReadFile(stdout.Name()); len(out) != 0 {
+		t.Errorf("command wrote %q to stdout", out)
+	}
+	if out, _ := os.ReadFile(stderr.Name()); string(out) != "notified +12125551234\n" {
+		t.Errorf("command wrote %q to stderr, want its output", out)
+	}
+}
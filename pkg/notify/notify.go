@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// Match is a number worth telling someone about
+type Match struct {
+	Number  string   `json:"number"`            // national significant number, ex. 2125551234
+	E164    string   `json:"e164"`              // ex. +12125551234
+	Score   float64  `json:"score"`             // vanity score
+	Tiers   []string `json:"tiers"`             // tiers the number matched, best first
+	Sources []string `json:"sources,omitempty"` // providers that offered the number
+}
+
+// NewMatch creates a Match for an E.164 number
+func NewMatch(e164 string, score float64, tiers, sources []string) Match {
+	return Match{
+		Number:  phone.NationalOf(e164),
+		E164:    e164,
+		Score:   score,
+		Tiers:   tiers,
+		Sources: sources,
+	}
+}
+
+// Notifier delivers matches to a single sink
+type Notifier interface {
+	Notify(matches []Match) error
+}
+
+// sink is a configured notifier along with the tiers it cares about
+type sink struct {
+	name  string
+	tiers []string
+	Notifier
+}
+
+// Dispatcher sends matches to every configured notifier
+type Dispatcher struct {
+	sinks []sink
+}
+
+// New builds a Dispatcher from notifier settings
+func New(cfgs []config.NotifierConfig) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for i, c := range cfgs {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("%s #%d", c.Type, i+1)
+		}
+
+		var n Notifier
+		var err error
+		switch strings.ToLower(c.Type) {
+		case "webhook":
+			n, err = newWebhook(c, webhookPayload)
+		case "slack", "discord":
+			n, err = newWebhook(c, chatPayload)
+		case "smtp", "email":
+			n, err = newSMTP(c)
+		case "command":
+			n, err = newCommand(c)
+		default:
+			err = fmt.Errorf("unknown notifier type '%s'", c.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+
+		d.sinks = append(d.sinks, sink{name: name, tiers: c.Tiers, Notifier: n})
+	}
+	return d, nil
+}
+
+// Empty reports whether no notifiers are configured
+func (d *Dispatcher) Empty() bool {
+	return d == nil || len(d.sinks) == 0
+}
+
+// Notify sends each notifier the matches in the tiers it accepts.
+// Every notifier is tried; their failures are joined into the returned error.
+func (d *Dispatcher) Notify(matches []Match) error {
+	if d.Empty() || len(matches) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, s := range d.sinks {
+		selected := filterTiers(matches, s.tiers)
+		if len(selected) == 0 {
+			continue
+		}
+		if err := s.Notify(selected); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// filterTiers keeps matches in at least one of tiers, or all matches if tiers is empty
+func filterTiers(matches []Match, tiers []string) []Match {
+	if len(tiers) == 0 {
+		return matches
+	}
+	var out []Match
+	for _, m := range matches {
+		for _, t := range m.Tiers {
+			if containsFold(tiers, t) {
+				out = append(out, m)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// containsFold reports whether s is in list, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// summary renders matches as plain text, one per line
+func summary(matches []Match) string {
+	var b strings.Builder
+	for _, m := range matches {
+		fmt.Fprintf(&b, "%s (%s, score %.1f)", m.E164, strings.Join(m.Tiers, ", "), m.Score)
+		if len(m.Sources) > 0 {
+			fmt.Fprintf(&b, " via %s", strings.Join(m.Sources, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+// mailer sends matches as a plain-text email
+type mailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func newSMTP(c config.NotifierConfig) (*mailer, error) {
+	if c.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	if c.From == "" || len(c.To) == 0 {
+		return nil, fmt.Errorf("from and to are required")
+	}
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	return &mailer{
+		addr:     net.JoinHostPort(c.Host, strconv.Itoa(port)),
+		host:     c.Host,
+		username: c.Username,
+		password: c.Password,
+		from:     c.From,
+		to:       c.To,
+	}, nil
+}
+
+// Notify sends one email listing every match
+func (m *mailer) Notify(matches []Match) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: milk found %d number(s)\r\n", len(matches))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(summary(matches), "\n", "\r\n"))
+
+	return smtp.SendMail(m.addr, auth, m.from, m.to, []byte(msg.String()))
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+// sentMail is a message received by a stand-in SMTP server
+type sentMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// newSMTPServer starts a minimal SMTP server on loopback accepting one message,
+// and returns its host and port
+func newSMTPServer(t *testing.T) (string, int, <-chan sentMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mail := make(chan sentMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+
+		var m sentMail
+		tp.PrintfLine("220 localhost ESMTP test")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL":
+				m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				m.to = append(m.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				lines, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				m.data = strings.Join(lines, "\n")
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				mail <- m
+				return
+			default:
+				tp.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, mail
+}
+
+func TestSMTP(t *testing.T) {
+	host, port, mail := newSMTPServer(t)
+	d, err := New([]config.NotifierConfig{{
+		Type: "smtp",
+		Host: host,
+		Port: port,
+		From: "milk@example.com",
+		To:   []string{"me@example.com", "you@example.com"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(testMatches); err != nil {
+		t.Fatal(err)
+	}
+
+	m := <-mail
+	if m.from != "milk@example.com" {
+		t.Errorf("MAIL FROM = %q, want milk@example.com", m.from)
+	}
+	if want := []string{"me@example.com", "you@example.com"}; !reflect.DeepEqual(m.to, want) {
+		t.Errorf("RCPT TO = %q, want %q", m.to, want)
+	}
+	for _, want := range []string{
+		"From: milk@example.com",
+		"To: me@example.com, you@example.com",
+		"Subject: milk found 2 number(s)",
+		"+12125551234 (VIP, score 42.5) via jmp",
+		"+17188888888 (Platinum, Notable, score 30.0)",
+	} {
+		if !strings.Contains(m.data, want) {
+			t.Errorf("message is missing %q:\n%s", want, m.data)
+		}
+	}
+}
+
+func TestSMTPRequired(t *testing.T) {
+	for _, c := range []config.NotifierConfig{
+		{Type: "smtp", From: "milk@example.com", To: []string{"me@example.com"}},
+		{Type: "email", Host: "localhost", Port: 25, To: []string{"me@example.com"}},
+		{Type: "smtp", Host: "localhost", From: "milk@example.com"},
+	} {
+		if _, err := New([]config.NotifierConfig{c}); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", c)
+		}
+	}
+}
+
+func TestSMTPDefaultPort(t *testing.T) {
+	m, err := newSMTP(config.NotifierConfig{Host: "mail.example.com", From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mail.example.com:587"; m.addr != want {
+		t.Errorf("addr = %q, want %q", m.addr, want)
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+// webhook POSTs matches as JSON to a URL
+type webhook struct {
+	url     string
+	headers map[string]string
+	payload func([]Match) any
+	client  *http.Client
+}
+
+func newWebhook(c config.NotifierConfig, payload func([]Match) any) (*webhook, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	return &webhook{
+		url:     c.URL,
+		headers: c.Headers,
+		payload: payload,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// webhookPayload is the generic body: {"matches": [...]}
+func webhookPayload(matches []Match) any {
+	return map[string]any{"matches": matches}
+}
+
+// chatPayload is understood by both Slack and Discord incoming webhooks
+func chatPayload(matches []Match) any {
+	text := fmt.Sprintf("milk found %d number(s):\n%s", len(matches), summary(matches))
+	return map[string]string{
+		"text":    text, // Slack
+		"content": text, // Discord
+	}
+}
+
+// Notify sends the payload and fails on any non-2xx response
+func (w *webhook) Notify(matches []Match) error {
+	body, err := json.Marshal(w.payload(matches))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
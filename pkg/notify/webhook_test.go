@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/milktart/milk/pkg/config"
+)
+
+var testMatches = []Match{
+	NewMatch("+12125551234", 42.5, []string{"VIP"}, []string{"jmp"}),
+	NewMatch("+17188888888", 30, []string{"Platinum", "Notable"}, nil),
+}
+
+// postedBody is a request received by a stand-in webhook server
+type postedBody struct {
+	header http.Header
+	body   []byte
+}
+
+// newWebhookServer starts a server recording what is posted to it and
+// answering with status
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, <-chan postedBody) {
+	t.Helper()
+	posts := make(chan postedBody, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("webhook received %s, want POST", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		posts <- postedBody{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, posts
+}
+
+func TestWebhook(t *testing.T) {
+	srv, posts := newWebhookServer(t, http.StatusNoContent)
+	d, err := New([]config.NotifierConfig{{
+		Type:    "webhook",
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(testMatches); err != nil {
+		t.Fatal(err)
+	}
+
+	post := <-posts
+	if got := post.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if got := post.header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+	var payload struct {
+		Matches []Match `json:"matches"`
+	}
+	if err := json.Unmarshal(post.body, &payload); err != nil {
+		t.Fatalf("payload %s: %v", post.body, err)
+	}
+	if !reflect.DeepEqual(payload.Matches, testMatches) {
+		t.Errorf("payload matches =\n%+v\nwant\n%+v", payload.Matches, testMatches)
+	}
+}
+
+func TestChatWebhook(t *testing.T) {
+	for _, typ := range []string{"slack", "discord"} {
+		t.Run(typ, func(t *testing.T) {
+			srv, posts := newWebhookServer(t, http.StatusOK)
+			d, err := New([]config.NotifierConfig{{Type: typ, URL: srv.URL}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Notify(testMatches); err != nil {
+				t.Fatal(err)
+			}
+
+			var payload map[string]string
+			post := <-posts
+			if err := json.Unmarshal(post.body, &payload); err != nil {
+				t.Fatalf("payload %s: %v", post.body, err)
+			}
+			want := "milk found 2 number(s):\n" +
+				"+12125551234 (VIP, score 42.5) via jmp\n" +
+				"+17188888888 (Platinum, Notable, score 30.0)\n"
+			for _, key := range []string{"text", "content"} {
+				if payload[key] != want {
+					t.Errorf("payload %s = %q, want %q", key, payload[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookTiers(t *testing.T) {
+	srv, posts := newWebhookServer(t, http.StatusOK)
+	d, err := New([]config.NotifierConfig{{Type: "webhook", URL: srv.URL, Tiers: []string{"notable"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(testMatches); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		Matches []Match `json:"matches"`
+	}
+	post := <-posts
+	if err := json.Unmarshal(post.body, &payload); err != nil {
+		t.Fatalf("payload %s: %v", post.body, err)
+	}
+	if len(payload.Matches) != 1 || payload.Matches[0].E164 != "+17188888888" {
+		t.Errorf("payload matches = %+v, want only +17188888888", payload.Matches)
+	}
+
+	// No match is in the tier, so nothing is sent
+	if err := d.Notify(testMatches[:1]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case post := <-posts:
+		t.Errorf("webhook received %s, want nothing", post.body)
+	default:
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	srv, _ := newWebhookServer(t, http.StatusInternalServerError)
+	d, err := New([]config.NotifierConfig{{Type: "slack", Name: "team", URL: srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Notify(testMatches)
+	if err == nil || !strings.Contains(err.Error(), "notifier team: webhook returned 500") {
+		t.Errorf("Notify error = %v, want the notifier name and status", err)
+	}
+}
@@ -0,0 +1,104 @@
+package phone
+
+import (
+	"strings"
+)
+
+// country describes how a country writes its numbers
+type country struct {
+	groups []int  // digit groups of the NSN, ex. 3,3,4
+	trunk  string // prefix dialled before the NSN within the country, ex. 0
+}
+
+// countries holds the countries with known layouts; others are grouped in threes
+var countries = map[string]country{
+	"1":  {groups: []int{3, 3, 4}},
+	"7":  {groups: []int{3, 3, 2, 2}, trunk: "8"},
+	"31": {groups: []int{1, 4, 4}, trunk: "0"},
+	"33": {groups: []int{1, 2, 2, 2, 2}, trunk: "0"},
+	"34": {groups: []int{3, 3, 3}},
+	"39": {groups: []int{3, 3, 4}},
+	"44": {groups: []int{4, 6}, trunk: "0"},
+	"49": {groups: []int{3, 4, 4}, trunk: "0"},
+	"61": {groups: []int{1, 4, 4}, trunk: "0"},
+	"64": {groups: []int{1, 3, 4}, trunk: "0"},
+	"81": {groups: []int{2, 4, 4}, trunk: "0"},
+}
+
+// Layout styles accepted by Number.Layout
+const (
+	StyleE164     = "e164"     // +12125551234
+	StyleIntl     = "intl"     // +1 (212) 555-1234, +44 2079 460958
+	StyleNational = "national" // (212) 555-1234, 02079 460958
+	StyleDashed   = "dashed"   // 212-555-1234
+	StyleDotted   = "dotted"   // 212.555.1234
+	StyleRaw      = "raw"      // 2125551234
+	StyleClassic  = "classic"  // the original three-part NANP layout
+)
+
+// Styles lists the layout styles
+var Styles = []string{StyleClassic, StyleDashed, StyleDotted, StyleE164, StyleIntl, StyleNational, StyleRaw}
+
+// Layout returns a layout for the number in the given style, with a # for each
+// digit of the NSN in order. Unknown styles fall back to StyleIntl.
+func (n Number) Layout(style string) string {
+	groups := n.Groups()
+	hashes := func(sep string) string {
+		parts := make([]string, len(groups))
+		for i, g := range groups {
+			parts[i] = strings.Repeat("#", g)
+		}
+		return strings.Join(parts, sep)
+	}
+
+	nanp := n.IsNANP() && len(n.National) == 10
+	switch style {
+	case StyleE164:
+		return "+" + n.Country + strings.Repeat("#", len(n.National))
+	case StyleRaw:
+		return strings.Repeat("#", len(n.National))
+	case StyleDashed:
+		return hashes("-")
+	case StyleDotted:
+		return hashes(".")
+	case StyleNational:
+		if nanp {
+			return "(###) ###-####"
+		}
+		return countries[n.Country].trunk + hashes(" ")
+	case StyleClassic:
+		if nanp {
+			return "+1 (###) ###-#### ///// +1-###-####### ///// ##########"
+		}
+	}
+	if nanp {
+		return "+1 (###) ###-####"
+	}
+	return "+" + n.Country + " " + hashes(" ")
+}
+
+// Groups splits the NSN length into the country's digit groups. Countries
+// without a known layout, or numbers of an unexpected length, use groups of 3
+// with a final group of up to 4.
+func (n Number) Groups() []int {
+	length := len(n.National)
+	if c, ok := countries[n.Country]; ok {
+		total := 0
+		for _, g := range c.groups {
+			total += g
+		}
+		if total == length {
+			return c.groups
+		}
+	}
+
+	var groups []int
+	for length > 4 {
+		groups = append(groups, 3)
+		length -= 3
+	}
+	if length > 0 {
+		groups = append(groups, length)
+	}
+	return groups
+}
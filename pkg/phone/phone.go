@@ -0,0 +1,118 @@
+// Package phone parses, splits and formats E.164 phone numbers.
+// Numbers are passed around as E.164 strings such as +12125551234; patterns
+// are matched against the national significant number (NSN) after the
+// country calling code, e.g. 2125551234.
+package phone
+
+import (
+	"strings"
+)
+
+// NANP is the calling code of the North American Numbering Plan
+const NANP = "1"
+
+// Country calling codes are prefix-free, so only the 1 and 2 digit codes need
+// listing: any number not starting with one of them has a 3 digit code
+var (
+	oneDigitCodes = map[string]bool{"1": true, "7": true}
+	twoDigitCodes = map[string]bool{
+		"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true,
+		"36": true, "39": true, "40": true, "41": true, "43": true, "44": true, "45": true,
+		"46": true, "47": true, "48": true, "49": true, "51": true, "52": true, "53": true,
+		"54": true, "55": true, "56": true, "57": true, "58": true, "60": true, "61": true,
+		"62": true, "63": true, "64": true, "65": true, "66": true, "81": true, "82": true,
+		"84": true, "86": true, "90": true, "91": true, "92": true, "93": true, "94": true,
+		"95": true, "98": true,
+	}
+)
+
+// Number is a phone number split into its country calling code and NSN
+type Number struct {
+	Country  string // calling code without +, ex. 1 or 44
+	National string // national significant number, ex. 2125551234
+}
+
+// E164 returns the number in +<country><national> form
+func (n Number) E164() string {
+	return "+" + n.Country + n.National
+}
+
+// IsNANP reports whether the number is in the North American Numbering Plan
+func (n Number) IsNANP() bool {
+	return n.Country == NANP
+}
+
+// Split parses an E.164 string into its country code and NSN
+func Split(e164 string) (Number, bool) {
+	digits, ok := strings.CutPrefix(e164, "+")
+	if !ok || len(digits) < 8 || len(digits) > 15 || !isDigits(digits) || digits[0] == '0' {
+		return Number{}, false
+	}
+	size := 3
+	switch {
+	case oneDigitCodes[digits[:1]]:
+		size = 1
+	case twoDigitCodes[digits[:2]]:
+		size = 2
+	}
+	n := Number{Country: digits[:size], National: digits[size:]}
+	if n.IsNANP() && len(n.National) != 10 {
+		return Number{}, false
+	}
+	return n, true
+}
+
+// Normalize reduces a phone number in any common notation to E.164. Numbers
+// starting with + are read as international (ex. +44 20 7946 0958); others
+// must be NANP numbers of 10 digits, or 11 starting with 1 (ex. (212) 555-1234).
+// NANP area codes and exchanges may not start with 0 or 1.
+func Normalize(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	digits := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, s[i])
+		}
+	}
+
+	if strings.HasPrefix(s, "+") {
+		e164 := "+" + string(digits)
+		n, ok := Split(e164)
+		if !ok || n.IsNANP() && !validNANP(n.National) {
+			return "", false
+		}
+		return e164, true
+	}
+
+	if len(digits) == 11 && digits[0] == '1' {
+		digits = digits[1:]
+	}
+	if len(digits) != 10 || !validNANP(string(digits)) {
+		return "", false
+	}
+	return "+" + NANP + string(digits), true
+}
+
+// validNANP reports whether a 10 digit NANP number has an area code and
+// exchange that do not start with 0 or 1
+func validNANP(national string) bool {
+	return national[0] >= '2' && national[3] >= '2'
+}
+
+// NationalOf returns the NSN of an E.164 number, or the string itself if it is not one
+func NationalOf(e164 string) string {
+	if n, ok := Split(e164); ok {
+		return n.National
+	}
+	return e164
+}
+
+// isDigits reports whether s is made only of ASCII digits
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,150 @@
+// Package query parses the searches sent to number providers.
+//
+// A query is one of:
+//
+//	212        area code: numbers in the area code
+//	212-555    area code and exchange: numbers starting 212-555
+//	5555       contains: 4 to 7 digits appearing anywhere in the number
+//	~8449988   partial: passed to the provider as written, letters allowed
+//
+// Area codes and exchanges may not start with 0 or 1.
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// Kind is the type of a query
+type Kind int
+
+const (
+	AreaCode Kind = iota
+	Exchange
+	Contains
+	Partial
+)
+
+// String returns the name of the kind as shown to the user
+func (k Kind) String() string {
+	switch k {
+	case AreaCode:
+		return "area code"
+	case Exchange:
+		return "exchange"
+	case Contains:
+		return "contains"
+	case Partial:
+		return "partial"
+	}
+	return "unknown"
+}
+
+// Query is a validated search
+type Query struct {
+	Kind Kind
+	Text string // the query as written
+}
+
+// Parse validates s against the query grammar
+func Parse(s string) (Query, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "~"):
+		rest := s[1:]
+		if rest == "" || len(rest) > 10 || !isAlnum(rest) {
+			return Query{}, fmt.Errorf("malformed partial query '%s': want ~ followed by 1 to 10 digits or letters", s)
+		}
+		return Query{Kind: Partial, Text: s}, nil
+
+	case len(s) == 7 && s[3] == '-':
+		if err := checkNXX(s[:3], "area code"); err != nil {
+			return Query{}, fmt.Errorf("malformed query '%s': %w", s, err)
+		}
+		if err := checkNXX(s[4:], "exchange"); err != nil {
+			return Query{}, fmt.Errorf("malformed query '%s': %w", s, err)
+		}
+		return Query{Kind: Exchange, Text: s}, nil
+
+	case len(s) == 3 && isDigits(s):
+		if err := checkNXX(s, "area code"); err != nil {
+			return Query{}, fmt.Errorf("malformed query '%s': %w", s, err)
+		}
+		return Query{Kind: AreaCode, Text: s}, nil
+
+	case len(s) >= 4 && len(s) <= 7 && isDigits(s):
+		return Query{Kind: Contains, Text: s}, nil
+	}
+
+	return Query{}, fmt.Errorf("malformed query '%s': want an area code (212), area code and exchange (212-555), "+
+		"4 to 7 digits to look for (5555) or a partial (~8449988)", s)
+}
+
+// Param returns the value sent to the provider. Exchanges are searched by
+// area code and contains queries as a partial, then narrowed by Match.
+func (q Query) Param() string {
+	switch q.Kind {
+	case Exchange:
+		return q.Text[:3]
+	case Contains:
+		return "~" + q.Text
+	}
+	return q.Text
+}
+
+// Match reports whether an E.164 number satisfies the query. Area codes and
+// exchanges only match NANP numbers. Partial queries are left to the provider
+// and match everything.
+func (q Query) Match(e164 string) bool {
+	n, ok := phone.Split(e164)
+	if !ok {
+		return false
+	}
+	switch q.Kind {
+	case AreaCode:
+		return n.IsNANP() && strings.HasPrefix(n.National, q.Text)
+	case Exchange:
+		return n.IsNANP() && strings.HasPrefix(n.National, q.Text[:3]+q.Text[4:])
+	case Contains:
+		return strings.Contains(n.National, q.Text)
+	}
+	return true
+}
+
+// String returns the query as written
+func (q Query) String() string {
+	return q.Text
+}
+
+// checkNXX validates a 3 digit area code or exchange
+func checkNXX(s, what string) error {
+	if len(s) != 3 || !isDigits(s) {
+		return fmt.Errorf("%s must be 3 digits", what)
+	}
+	if s[0] == '0' || s[0] == '1' {
+		return fmt.Errorf("%s may not start with 0 or 1", what)
+	}
+	return nil
+}
+
+// isDigits reports whether s is made only of ASCII digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isAlnum reports whether s is made only of ASCII digits and letters
+func isAlnum(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
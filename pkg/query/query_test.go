@@ -0,0 +1,96 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		kind  Kind
+		text  string
+		param string
+	}{
+		{"212", AreaCode, "212", "212"},
+		{" 808 ", AreaCode, "808", "808"},
+		{"212-555", Exchange, "212-555", "212"},
+		{"5555", Contains, "5555", "~5555"},
+		{"8449988", Contains, "8449988", "~8449988"},
+		{"~8449988", Partial, "~8449988", "~8449988"},
+		{"~CALLME", Partial, "~CALLME", "~CALLME"},
+		{"~1", Partial, "~1", "~1"},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if q.Kind != tt.kind || q.Text != tt.text {
+			t.Errorf("Parse(%q) = %s %q, want %s %q", tt.in, q.Kind, q.Text, tt.kind, tt.text)
+		}
+		if got := q.Param(); got != tt.param {
+			t.Errorf("Parse(%q).Param() = %q, want %q", tt.in, got, tt.param)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "malformed query ''"},
+		{"21", "want an area code"},
+		{"012", "area code may not start with 0 or 1"},
+		{"112", "area code may not start with 0 or 1"},
+		{"212-055", "exchange may not start with 0 or 1"},
+		{"212-15a", "exchange must be 3 digits"},
+		{"2a2-555", "area code must be 3 digits"},
+		{"12345678", "want an area code"},
+		{"212 555", "want an area code"},
+		{"~", "malformed partial query"},
+		{"~12345678901", "malformed partial query"},
+		{"~844-9988", "malformed partial query"},
+		{"NYC", "want an area code"},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v, want error %q", tt.in, q, err, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		e164  string
+		want  bool
+	}{
+		{"212", "+12125551234", true},
+		{"212", "+16465551234", false},
+		{"212", "+442125551234", false}, // area codes are NANP only
+		{"212-555", "+12125551234", true},
+		{"212-555", "+12125561234", false},
+		{"212-555", "+16462125551", false},
+		{"5555", "+12125555123", true},
+		{"5555", "+17185551234", false},
+		{"5555", "+442075555123", true},
+		{"~8449988", "+16465551234", true}, // left to the provider
+		{"212", "2125551234", false},
+	}
+
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.Match(tt.e164); got != tt.want {
+			t.Errorf("Parse(%q).Match(%q) = %v, want %v", tt.query, tt.e164, got, tt.want)
+		}
+	}
+}
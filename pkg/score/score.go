@@ -0,0 +1,96 @@
+package score
+
+import (
+	"math"
+
+	"github.com/milktart/milk/pkg/config"
+	"github.com/milktart/milk/pkg/phone"
+	"github.com/milktart/milk/pkg/vanity"
+)
+
+// subscriberDigits is how many trailing digits the digit signals look at.
+// The area code is chosen by the searcher, so only the rest says anything about the number.
+const subscriberDigits = 7
+
+// Score rates how memorable an E.164 number is: the weights of every pattern
+// it matches across all tiers, a bonus for spelling a word, plus entropy, run
+// and distinct-digit signals over its national number.
+//
+// matched reports whether the number matched the pattern with regex in tier.
+// It is answered from the number's classification rather than by running the
+// patterns again, so a pattern that timed out there adds nothing here.
+func Score(e164 string, cfg *config.Config, matched func(tier, regex string) bool) float64 {
+	n, ok := phone.Split(e164)
+	if !ok {
+		return 0
+	}
+	number := n.National
+
+	total := 0.0
+	for _, tier := range cfg.CompiledTiers {
+		for _, p := range tier.Patterns {
+			if p.AppliesTo(n.Country) && matched(tier.Name, p.Regex) {
+				total += p.Weight
+			}
+		}
+	}
+
+	if word, _, ok := cfg.CompiledWords.Match(number); ok {
+		total += cfg.Scoring.Vanity * float64(len(word)) / vanity.MaxLength
+	}
+
+	digits := number
+	if len(digits) > subscriberDigits {
+		digits = digits[len(digits)-subscriberDigits:]
+	}
+	total += cfg.Scoring.Entropy * (1 - Entropy(digits)/math.Log2(subscriberDigits))
+	total += cfg.Scoring.Runs * float64(LongestRun(digits)-1)
+	total += cfg.Scoring.Distinct * float64(subscriberDigits-Distinct(digits))
+
+	return math.Round(total*10) / 10
+}
+
+// Entropy returns the Shannon entropy of the digits in s, in bits
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	h := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// LongestRun returns the length of the longest run of one repeated character
+func LongestRun(s string) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i] == s[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// Distinct returns the number of different characters in s
+func Distinct(s string) int {
+	seen := make(map[byte]struct{}, len(s))
+	for i := 0; i < len(s); i++ {
+		seen[s[i]] = struct{}{}
+	}
+	return len(seen)
+}
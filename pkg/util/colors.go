@@ -1,11 +1,53 @@
 package util
 
+import "strings"
+
 // ANSI color codes
 const (
 	RED       = "\033[1;91m"
 	GREEN     = "\033[1;92m"
 	YELLOW    = "\033[1;93m"
 	BLUE      = "\033[1;94m"
+	MAGENTA   = "\033[1;95m"
+	CYAN      = "\033[1;96m"
+	WHITE     = "\033[1;97m"
 	BLUEBLINK = "\033[1;5;94m"
 	NC        = "\033[0m"
 )
+
+var colorNames = map[string]string{
+	"red":     RED,
+	"green":   GREEN,
+	"yellow":  YELLOW,
+	"blue":    BLUE,
+	"magenta": MAGENTA,
+	"cyan":    CYAN,
+	"white":   WHITE,
+}
+
+// colorEnabled controls whether Paint and PrintNumbers emit color codes
+var colorEnabled = true
+
+// SetColor turns colored output on or off
+func SetColor(on bool) {
+	colorEnabled = on
+}
+
+// ColorEnabled reports whether colored output is on
+func ColorEnabled() bool {
+	return colorEnabled
+}
+
+// Paint wraps s in color, or returns it unchanged when color is off
+func Paint(color, s string) string {
+	if !colorEnabled || color == "" {
+		return s
+	}
+	return color + s + NC
+}
+
+// ColorByName returns the ANSI code for a color name such as "red"
+func ColorByName(name string) (string, bool) {
+	c, ok := colorNames[strings.ToLower(name)]
+	return c, ok
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 var ansiRE = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
@@ -13,19 +14,118 @@ func StripANSI(s string) string {
 	return ansiRE.ReplaceAllString(s, "")
 }
 
-// PrintNumbers prints a formatted list of phone numbers
-func PrintNumbers(title string, numbers []string) {
-	if len(numbers) == 0 {
+// Entry is a phone number to print along with what is known about it
+type Entry struct {
+	Number     string   // E.164 number, ex. +12125551234
+	Score      float64  // vanity score
+	Sources    []string // providers that offered the number
+	Highlights [][2]int // [start, end) spans of the national number's digits to color
+	Patterns   []string // names of the patterns the number matched
+	Word       string   // number spelled with a vanity word, ex. 415-FLOWERS
+	Location   string   // where the provider says the number is, ex. Brooklyn, NY
+	RateCenter string   // telco rate center given by the provider, ex. NWYRCYZN01
+	Price      string   // price asked by the provider, ex. $5.00
+	Details    []string // anything else the provider says, ex. lata=132
+}
+
+// PrintNumbers prints a list of phone numbers in each of formats with their
+// scores, showing the title and the digits that matched a pattern in color.
+// When known, each number's location, rate center, price, other provider
+// details and the providers that offered it are shown, and with explain the
+// patterns it matched.
+func PrintNumbers(title string, color string, entries []Entry, formats []NumberFormat, explain bool) {
+	if len(entries) == 0 {
 		return
 	}
-	fmt.Println(title)
-	for _, n := range numbers {
-		if len(n) < 10 {
+	if len(formats) == 0 {
+		formats = []NumberFormat{{style: DefaultNumberFormat}}
+	}
+
+	numbers := make([]string, len(entries))
+	width := 0
+	for i, e := range entries {
+		highlights := e.Highlights
+		if !colorEnabled {
+			highlights = nil
+		}
+		parts := make([]string, len(formats))
+		for j, f := range formats {
+			parts[j] = f.Render(e.Number, highlights, color)
+		}
+		numbers[i] = strings.Join(parts, "  ")
+		width = max(width, utf8.RuneCountInString(StripANSI(numbers[i])))
+	}
+
+	fmt.Println(Paint(color, title))
+	for i, e := range entries {
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(StripANSI(numbers[i])))
+		fmt.Printf("  %s%s  %5.1f", numbers[i], pad, e.Score)
+		if e.Word != "" {
+			fmt.Printf("  %s", e.Word)
+		}
+		if e.Location != "" {
+			fmt.Printf("  %s", e.Location)
+		}
+		if e.RateCenter != "" {
+			fmt.Printf("  %s", e.RateCenter)
+		}
+		if e.Price != "" {
+			fmt.Printf("  %s", e.Price)
+		}
+		if len(e.Details) > 0 {
+			fmt.Printf("  %s", strings.Join(e.Details, " "))
+		}
+		if len(e.Sources) > 0 {
+			fmt.Printf(" [%s]", strings.Join(e.Sources, ", "))
+		}
+		if explain && len(e.Patterns) > 0 {
+			fmt.Printf("  <- %s", strings.Join(e.Patterns, "; "))
+		}
+		fmt.Println()
+	}
+}
+
+// fillDigits substitutes the digits of n into layout, repeating n as often as
+// layout asks and coloring the digits inside any of the highlight spans
+func fillDigits(layout, n string, highlights [][2]int, color string) string {
+	var b strings.Builder
+	i := 0
+	colored := false
+	for _, r := range layout {
+		if r != '#' {
+			if colored {
+				b.WriteString(NC)
+				colored = false
+			}
+			b.WriteRune(r)
 			continue
 		}
-		fmt.Printf("  +1 (%s) %s-%s ///// +1-%s-%s%s ///// %s\n",
-			n[:3], n[3:6], n[6:10], n[:3], n[3:6], n[6:10], n)
+		d := i % len(n)
+		i++
+		if hl := inSpans(d, highlights); hl != colored {
+			if hl {
+				b.WriteString(color)
+			} else {
+				b.WriteString(NC)
+			}
+			colored = hl
+		}
+		b.WriteByte(n[d])
+	}
+	if colored {
+		b.WriteString(NC)
+	}
+	return b.String()
+}
+
+// inSpans reports whether index i falls inside any [start, end) span
+func inSpans(i int, spans [][2]int) bool {
+	for _, s := range spans {
+		if i >= s[0] && i < s[1] {
+			return true
+		}
 	}
+	return false
 }
 
 // SplitList parses a comma or space separated list into a slice
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/milktart/milk/pkg/phone"
+)
+
+// DefaultNumberFormat is the preset used when no format is chosen
+const DefaultNumberFormat = phone.StyleIntl
+
+// NumberFormat renders an E.164 number in a preset style or with a Go template
+type NumberFormat struct {
+	style string             // preset style, or "" for a template
+	tmpl  *template.Template // custom template, or nil for a preset
+}
+
+// NumberFields are the values available to a custom format template,
+// ex. --format '{{.AreaCode}}/{{.Exchange}}-{{.Line}}'. For numbers outside
+// the NANP, AreaCode, Exchange and Line are the first, second and remaining
+// digit groups of the national number.
+type NumberFields struct {
+	Number      string // national significant number, ex. 2125551234
+	E164        string // ex. +12125551234
+	CountryCode string // ex. 1
+	AreaCode    string
+	Exchange    string
+	Line        string
+}
+
+// ParseNumberFormat returns the named preset, or parses s as a template if it contains {{
+func ParseNumberFormat(s string) (NumberFormat, error) {
+	if strings.Contains(s, "{{") {
+		tmpl, err := template.New("format").Parse(s)
+		if err != nil {
+			return NumberFormat{}, fmt.Errorf("failed to parse format template: %w", err)
+		}
+		if err := tmpl.Execute(io.Discard, NumberFields{}); err != nil {
+			return NumberFormat{}, fmt.Errorf("invalid format template: %w", err)
+		}
+		return NumberFormat{tmpl: tmpl}, nil
+	}
+	style := strings.ToLower(s)
+	if !slices.Contains(phone.Styles, style) {
+		return NumberFormat{}, fmt.Errorf("unknown number format '%s' (available: %s, or a template such as '{{.AreaCode}}-{{.Line}}')",
+			s, strings.Join(NumberFormatNames(), ", "))
+	}
+	return NumberFormat{style: style}, nil
+}
+
+// NumberFormatNames returns the names of the presets, sorted
+func NumberFormatNames() []string {
+	return phone.Styles
+}
+
+// Render formats an E.164 number in its country's layout, coloring the digits
+// of the national number inside highlights for presets. Strings that are not
+// E.164 numbers are returned as they are.
+func (f NumberFormat) Render(e164 string, highlights [][2]int, color string) string {
+	n, ok := phone.Split(e164)
+	if !ok {
+		return e164
+	}
+	if f.tmpl == nil {
+		return fillDigits(n.Layout(f.style), n.National, highlights, color)
+	}
+
+	fields := NumberFields{Number: n.National, E164: e164, CountryCode: n.Country}
+	rest := n.National
+	for i, g := range n.Groups() {
+		switch i {
+		case 0:
+			fields.AreaCode, rest = rest[:g], rest[g:]
+		case 1:
+			fields.Exchange, rest = rest[:g], rest[g:]
+		}
+	}
+	fields.Line = rest
+
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, fields); err != nil {
+		return e164
+	}
+	return b.String()
+}
+
+// NumberFormats is a flag.Value collecting one or more formats. Presets may be
+// given as a comma separated list; a template is always taken whole.
+type NumberFormats []NumberFormat
+
+// String is required by flag.Value
+func (fs *NumberFormats) String() string {
+	return ""
+}
+
+// Set adds the formats in s
+func (fs *NumberFormats) Set(s string) error {
+	parts := []string{s}
+	if !strings.Contains(s, "{{") {
+		parts = SplitList(s)
+	}
+	for _, p := range parts {
+		f, err := ParseNumberFormat(p)
+		if err != nil {
+			return err
+		}
+		*fs = append(*fs, f)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package vanity
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Shortest and longest words matched against the end of a number
+const (
+	MinLength = 4
+	MaxLength = 7
+)
+
+// keypad maps letters to the digit they share a key with
+var keypad = map[rune]byte{
+	'A': '2', 'B': '2', 'C': '2',
+	'D': '3', 'E': '3', 'F': '3',
+	'G': '4', 'H': '4', 'I': '4',
+	'J': '5', 'K': '5', 'L': '5',
+	'M': '6', 'N': '6', 'O': '6',
+	'P': '7', 'Q': '7', 'R': '7', 'S': '7',
+	'T': '8', 'U': '8', 'V': '8',
+	'W': '9', 'X': '9', 'Y': '9', 'Z': '9',
+}
+
+// Dictionary looks up words by the digits they spell on a phone keypad
+type Dictionary struct {
+	byDigits map[string][]string
+}
+
+// NewDictionary indexes words of MinLength to MaxLength letters.
+// Words that are too short or long, or contain non-letters, are skipped.
+func NewDictionary(words []string) *Dictionary {
+	d := &Dictionary{byDigits: make(map[string][]string)}
+	for _, w := range words {
+		w = strings.ToUpper(strings.TrimSpace(w))
+		if len(w) < MinLength || len(w) > MaxLength {
+			continue
+		}
+		digits, ok := ToDigits(w)
+		if !ok {
+			continue
+		}
+		if !contains(d.byDigits[digits], w) {
+			d.byDigits[digits] = append(d.byDigits[digits], w)
+		}
+	}
+	return d
+}
+
+// ParseWords reads a word list with one word per line; blank lines and # comments are ignored
+func ParseWords(data []byte) []string {
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words
+}
+
+// ToDigits returns the keypad digits that spell word
+func ToDigits(word string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(word) {
+		d, ok := keypad[r]
+		if !ok {
+			return "", false
+		}
+		b.WriteByte(d)
+	}
+	return b.String(), true
+}
+
+// Len returns the number of distinct digit sequences in the dictionary
+func (d *Dictionary) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.byDigits)
+}
+
+// Match finds the longest word spelled by the last 4-7 digits of number.
+// It returns the word and the index in number where it starts.
+func (d *Dictionary) Match(number string) (string, int, bool) {
+	if d.Len() == 0 {
+		return "", 0, false
+	}
+	for n := MaxLength; n >= MinLength; n-- {
+		if n > len(number) {
+			continue
+		}
+		start := len(number) - n
+		if words, ok := d.byDigits[number[start:]]; ok {
+			return words[0], start, true
+		}
+	}
+	return "", 0, false
+}
+
+// Spell writes number with its last digits replaced by word, ex. 415-FLOWERS or 212-555-CAFE
+func Spell(number, word string) string {
+	if len(word) > len(number) {
+		return word
+	}
+	rest := number[:len(number)-len(word)]
+	if len(rest) <= 3 {
+		return rest + "-" + word
+	}
+	return rest[:3] + "-" + rest[3:] + "-" + word
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}